@@ -0,0 +1,126 @@
+package priorty_queue
+
+import (
+	"context"
+	"sync"
+)
+
+// BlockingPriorityQueue is a goroutine-safe PriorityQueue whose Pop waits for
+// an item to arrive instead of failing on an empty queue. Waiting consumers
+// sleep on a condition variable that Push signals, so nothing busy-waits.
+// Use NewBlockingPriorityQueue to create one.
+type BlockingPriorityQueue[T any] struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	pq   PriorityQueue[T]
+	// topChanged carries the latest top priority to TopChanged's reader.
+	topChanged chan int64
+}
+
+// NewBlockingPriorityQueue returns an empty BlockingPriorityQueue with room
+// for capacity items. A negative capacity is treated as 0.
+func NewBlockingPriorityQueue[T any](capacity int) *BlockingPriorityQueue[T] {
+	q := &BlockingPriorityQueue[T]{
+		pq:         *NewPriorityQueue(WithCapacity[T](capacity)),
+		topChanged: make(chan int64, 1),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push adds item to the queue and wakes one waiting Pop. Like PushItem, it
+// returns false, changing nothing and waking no one, if item is nil or
+// already queued.
+func (q *BlockingPriorityQueue[T]) Push(item *Item[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	prev, had := q.pq.PeekPriority()
+	if !q.pq.PushItem(item) {
+		return false
+	}
+	q.notifyTop(prev, had)
+	q.cond.Signal()
+	return true
+}
+
+// Update changes the priority of item and restores the heap ordering. It
+// returns false if item is not currently queued.
+func (q *BlockingPriorityQueue[T]) Update(item *Item[T], priority int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	prev, had := q.pq.PeekPriority()
+	if !q.pq.Update(item, priority) {
+		return false
+	}
+	q.notifyTop(prev, had)
+	return true
+}
+
+// Pop removes and returns the top item, blocking until one is available. It
+// returns ctx.Err() if ctx is done before an item arrives.
+func (q *BlockingPriorityQueue[T]) Pop(ctx context.Context) (*Item[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pq.Len() == 0 {
+		// Wake every waiter when ctx is done so this one can notice.
+		stop := context.AfterFunc(ctx, func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.cond.Broadcast()
+		})
+		defer stop()
+		for q.pq.Len() == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			q.cond.Wait()
+		}
+	}
+	prev := q.pq.items[0].priority
+	item, _ := q.pq.PopItem()
+	q.notifyTop(prev, true)
+	return item, nil
+}
+
+// TryPop removes and returns the top item without blocking. ok is false when
+// the queue is empty.
+func (q *BlockingPriorityQueue[T]) TryPop() (*Item[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	prev, had := q.pq.PeekPriority()
+	item, ok := q.pq.PopItem()
+	q.notifyTop(prev, had)
+	return item, ok
+}
+
+// Len returns the number of items in the queue.
+func (q *BlockingPriorityQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Len()
+}
+
+// TopChanged returns a channel that receives the top priority whenever a
+// Push, Pop or Update changes it, so a consumer can re-arm a timer. Changes
+// are coalesced: the channel buffers one value, and a newer priority replaces
+// one that has not been read yet, so sends never block and a slow reader sees
+// only the latest top. Nothing is sent when the queue becomes empty.
+func (q *BlockingPriorityQueue[T]) TopChanged() <-chan int64 {
+	return q.topChanged
+}
+
+// notifyTop publishes the top priority if it differs from prev, the top
+// priority before the change (had is false if the queue was empty). The
+// caller must hold q.mu.
+func (q *BlockingPriorityQueue[T]) notifyTop(prev int64, had bool) {
+	top, ok := q.pq.PeekPriority()
+	if !ok || (had && top == prev) {
+		return
+	}
+	// Replace any unread value so the reader always sees the latest top.
+	select {
+	case <-q.topChanged:
+	default:
+	}
+	q.topChanged <- top
+}
@@ -0,0 +1,134 @@
+package priorty_queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBlockingPopCanceled(t *testing.T) {
+	q := NewBlockingPriorityQueue[string](0)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := q.Pop(ctx)
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Pop() = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not return after cancel")
+	}
+}
+
+func TestBlockingPopWakesOnPush(t *testing.T) {
+	q := NewBlockingPriorityQueue[string](0)
+	got := make(chan *Item[string])
+	go func() {
+		item, err := q.Pop(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		got <- item
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Push(NewItem("late", 1))
+	select {
+	case item := <-got:
+		if item.value != "late" {
+			t.Fatalf("Pop() = %q, want late", item.value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not wake on Push")
+	}
+}
+
+func TestBlockingTryPop(t *testing.T) {
+	q := NewBlockingPriorityQueue[string](0)
+	if _, ok := q.TryPop(); ok {
+		t.Fatal("TryPop() on empty = ok")
+	}
+	q.Push(NewItem("b", 2))
+	q.Push(NewItem("a", 1))
+	if item, ok := q.TryPop(); !ok || item.value != "a" || q.Len() != 1 {
+		t.Errorf("TryPop() = %v, %v with Len %d", item, ok, q.Len())
+	}
+}
+
+func TestTopChanged(t *testing.T) {
+	q := NewBlockingPriorityQueue[string](0)
+	ch := q.TopChanged()
+	expect := func(want int64) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("TopChanged delivered %d, want %d", got, want)
+			}
+		default:
+			t.Errorf("TopChanged delivered nothing, want %d", want)
+		}
+	}
+	expectNone := func() {
+		t.Helper()
+		select {
+		case got := <-ch:
+			t.Errorf("TopChanged delivered %d, want nothing", got)
+		default:
+		}
+	}
+
+	q.Push(NewItem("deadline", 500))
+	expect(500)
+	q.Push(NewItem("later", 900))
+	expectNone()
+	earlier := NewItem("earlier", 200)
+	q.Push(earlier)
+	expect(200)
+
+	// Unread changes coalesce into the latest top.
+	q.Update(earlier, 100)
+	q.Push(NewItem("earliest", 50))
+	expect(50)
+	expectNone()
+
+	q.TryPop()
+	expect(100)
+	q.TryPop()
+	q.TryPop()
+	q.TryPop()
+	expect(900)
+	expectNone()
+}
+
+func TestBlockingPushRejectsDuplicates(t *testing.T) {
+	q := NewBlockingPriorityQueue[string](0)
+	a := NewItem("a", 1)
+	if !q.Push(a) {
+		t.Fatal("Push of a new item = false")
+	}
+	<-q.TopChanged()
+	if q.Push(a) || q.Push(nil) {
+		t.Error("Push of a queued or nil item = true")
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", q.Len())
+	}
+	select {
+	case p := <-q.TopChanged():
+		t.Errorf("rejected push published top %d", p)
+	default:
+	}
+	if item, ok := q.TryPop(); !ok || item != a {
+		t.Fatalf("TryPop() = %v, %v, want a", item, ok)
+	}
+	if item, ok := q.TryPop(); ok {
+		t.Errorf("TryPop() = %v after draining the only item", item.value)
+	}
+}
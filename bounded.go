@@ -0,0 +1,55 @@
+package priorty_queue
+
+// BoundedPriorityQueue holds at most a fixed number of items, keeping the
+// highest priorities (the most recent timestamps) once it is full. Pop still
+// returns the lowest priority first, like PriorityQueue.
+//
+// A plain binary heap only knows one end cheaply, so the queue is backed by a
+// MinMaxPriorityQueue: the lowest priority is the eviction candidate and both
+// ends are reachable in O(log n).
+type BoundedPriorityQueue[T any] struct {
+	max int
+	mm  MinMaxPriorityQueue[T]
+}
+
+// NewBoundedPriorityQueue returns an empty queue that holds at most max items.
+// A negative max is treated as 0, giving a queue that accepts nothing.
+func NewBoundedPriorityQueue[T any](max int) *BoundedPriorityQueue[T] {
+	if max < 0 {
+		max = 0
+	}
+	return &BoundedPriorityQueue[T]{
+		max: max,
+		mm:  MinMaxPriorityQueue[T]{items: make([]*Item[T], 0, max)},
+	}
+}
+
+// Push adds item to the queue. When the queue is full, the incoming item is
+// dropped (accepted is false) unless its priority is higher than the current
+// lowest, in which case that lowest item is evicted and returned so the
+// caller can clean it up.
+func (q *BoundedPriorityQueue[T]) Push(item *Item[T]) (evicted *Item[T], accepted bool) {
+	if q.mm.Len() < q.max {
+		q.mm.PushItem(item)
+		return nil, true
+	}
+	lowest, ok := q.mm.PeekMin()
+	if !ok || item.priority <= lowest.priority {
+		return nil, false
+	}
+	evicted, _ = q.mm.PopMin()
+	q.mm.PushItem(item)
+	return evicted, true
+}
+
+// Pop removes and returns the lowest-priority item.
+func (q *BoundedPriorityQueue[T]) Pop() (*Item[T], bool) { return q.mm.PopMin() }
+
+// Peek returns the lowest-priority item without removing it.
+func (q *BoundedPriorityQueue[T]) Peek() (*Item[T], bool) { return q.mm.PeekMin() }
+
+// Len returns the number of items in the queue.
+func (q *BoundedPriorityQueue[T]) Len() int { return q.mm.Len() }
+
+// Cap returns the maximum number of items the queue holds.
+func (q *BoundedPriorityQueue[T]) Cap() int { return q.max }
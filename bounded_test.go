@@ -0,0 +1,39 @@
+package priorty_queue
+
+import "testing"
+
+func TestBoundedKeepsNewest(t *testing.T) {
+	const max = 10
+	q := NewBoundedPriorityQueue[int](max)
+	evictions := 0
+	for i := range max + 5 {
+		// Push in a scrambled order so evictions are not just the oldest push.
+		p := int64((i * 7) % (max + 5))
+		evicted, accepted := q.Push(NewItem(int(p), p))
+		if !accepted && evicted != nil {
+			t.Fatalf("rejected push evicted %v", evicted.value)
+		}
+		if evicted != nil {
+			evictions++
+		}
+		if q.Len() > max {
+			t.Fatalf("Len() = %d after push %d, beyond %d", q.Len(), i, max)
+		}
+	}
+	if q.Len() != max || q.Cap() != max {
+		t.Fatalf("Len(), Cap() = %d, %d; want %d", q.Len(), q.Cap(), max)
+	}
+	// The survivors are the ten highest priorities, 5 through 14.
+	for want := int64(5); want < max+5; want++ {
+		item, ok := q.Pop()
+		if !ok || item.priority != want {
+			t.Fatalf("Pop() = %v, %v; want priority %d", item, ok, want)
+		}
+	}
+	if evictions == 0 {
+		t.Error("no push evicted an item")
+	}
+	if _, accepted := NewBoundedPriorityQueue[int](-1).Push(NewItem(0, 0)); accepted {
+		t.Error("a zero-size queue accepted a push")
+	}
+}
@@ -0,0 +1,113 @@
+package priorty_queue
+
+// BucketPriorityQueue is a min-priority queue for priorities that cluster in
+// a known window, such as millisecond timestamps over the next minute. The
+// window [base, base+width*buckets) is cut into fixed-width buckets, each a
+// FIFO list, so Push is O(1) and Pop is amortized O(1) as the scan for the
+// lowest non-empty bucket only moves forward between pushes into lower
+// buckets. Priorities outside the window go to an ordinary heap.
+//
+// Ordering is quantized to the bucket width: items in the same bucket pop in
+// push order whatever their exact priorities, so the order matches a
+// PriorityQueue exactly only when width is 1. Use NewBucketPriorityQueue to
+// create one.
+type BucketPriorityQueue[T any] struct {
+	base, width int64
+	buckets     []bucket[T]
+	// cursor is the lowest bucket that may be non-empty.
+	cursor int
+	// inRange counts the items held in buckets.
+	inRange int
+	// overflow holds items whose priority falls outside the window.
+	overflow PriorityQueue[T]
+}
+
+// bucket is a FIFO list of items; items[head:] are still queued.
+type bucket[T any] struct {
+	items []*Item[T]
+	head  int
+}
+
+// NewBucketPriorityQueue returns an empty queue whose window starts at base
+// and spans buckets buckets of width priorities each. width and buckets below
+// 1 are treated as 1.
+func NewBucketPriorityQueue[T any](base, width int64, buckets int) *BucketPriorityQueue[T] {
+	return &BucketPriorityQueue[T]{
+		base:    base,
+		width:   max(width, 1),
+		buckets: make([]bucket[T], max(buckets, 1)),
+	}
+}
+
+// Len returns the number of items in the queue.
+func (q *BucketPriorityQueue[T]) Len() int { return q.inRange + q.overflow.Len() }
+
+// Push adds item to the queue.
+func (q *BucketPriorityQueue[T]) Push(item *Item[T]) {
+	i, ok := q.bucketFor(item.priority)
+	if !ok {
+		q.overflow.PushItem(item)
+		return
+	}
+	b := &q.buckets[i]
+	b.items = append(b.items, item)
+	q.inRange++
+	q.cursor = min(q.cursor, i)
+}
+
+// Pop removes and returns the lowest-priority item. ok is false when the
+// queue is empty.
+func (q *BucketPriorityQueue[T]) Pop() (*Item[T], bool) {
+	b := q.top()
+	if b == nil {
+		return q.overflow.PopItem()
+	}
+	item := b.items[b.head]
+	b.items[b.head] = nil
+	b.head++
+	if b.head == len(b.items) {
+		b.items, b.head = b.items[:0], 0
+	}
+	item.index = -1 // for safety
+	q.inRange--
+	return item, true
+}
+
+// Peek returns the lowest-priority item without removing it. ok is false
+// when the queue is empty.
+func (q *BucketPriorityQueue[T]) Peek() (*Item[T], bool) {
+	if b := q.top(); b != nil {
+		return b.items[b.head], true
+	}
+	return q.overflow.Peek()
+}
+
+// top returns the bucket holding the next item to pop, or nil if that item
+// is in the overflow heap.
+func (q *BucketPriorityQueue[T]) top() *bucket[T] {
+	if p, ok := q.overflow.PeekPriority(); ok && p < q.base {
+		return nil
+	}
+	if q.inRange == 0 {
+		return nil
+	}
+	for q.buckets[q.cursor].head == len(q.buckets[q.cursor].items) {
+		q.cursor++
+	}
+	return &q.buckets[q.cursor]
+}
+
+// bucketFor returns the bucket covering priority, or false if priority lies
+// outside the window.
+func (q *BucketPriorityQueue[T]) bucketFor(priority int64) (int, bool) {
+	if priority < q.base {
+		return 0, false
+	}
+	// The unsigned difference stays exact even when priority-base overflows
+	// int64.
+	i := uint64(priority-q.base) / uint64(q.width)
+	if i >= uint64(len(q.buckets)) {
+		return 0, false
+	}
+	return int(i), true
+}
@@ -0,0 +1,103 @@
+package priorty_queue
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestBucketMatchesHeap(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 8))
+	const base, buckets = 1000, 64
+	bq := NewBucketPriorityQueue[int](base, 1, buckets)
+	pq := NewPriorityQueue[int]()
+	for i := range 5000 {
+		if r.IntN(3) == 0 {
+			b, bok := bq.Pop()
+			p, pok := pq.PopItem()
+			if bok != pok {
+				t.Fatalf("op %d: Pop ok = %v, heap %v", i, bok, pok)
+			}
+			if bok && (b.value != p.value || b.priority != p.priority) {
+				t.Fatalf("op %d: Pop = %d@%d, heap %d@%d", i, b.value, b.priority, p.value, p.priority)
+			}
+			continue
+		}
+		// Mostly inside the window, with some below and above it.
+		priority := int64(base - 8 + r.IntN(buckets+16))
+		bq.Push(NewItem(i, priority))
+		pq.PushValue(i, priority)
+		if bq.Len() != pq.Len() {
+			t.Fatalf("op %d: Len() = %d, heap %d", i, bq.Len(), pq.Len())
+		}
+		b, _ := bq.Peek()
+		p, _ := pq.Peek()
+		if b.value != p.value {
+			t.Fatalf("op %d: Peek() = %d, heap %d", i, b.value, p.value)
+		}
+	}
+	for {
+		b, bok := bq.Pop()
+		p, pok := pq.PopItem()
+		if bok != pok {
+			t.Fatalf("drain: Pop ok = %v, heap %v", bok, pok)
+		}
+		if !bok {
+			break
+		}
+		if b.value != p.value {
+			t.Fatalf("drain: Pop = %d, heap %d", b.value, p.value)
+		}
+	}
+}
+
+func TestBucketQuantizesWithinWidth(t *testing.T) {
+	bq := NewBucketPriorityQueue[string](0, 10, 4)
+	bq.Push(NewItem("late-in-bucket", 9))
+	bq.Push(NewItem("early-in-bucket", 1))
+	bq.Push(NewItem("next", 10))
+	var got []string
+	for {
+		item, ok := bq.Pop()
+		if !ok {
+			break
+		}
+		got = append(got, item.value)
+	}
+	want := []string{"late-in-bucket", "early-in-bucket", "next"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pop order %v, want %v", got, want)
+		}
+	}
+}
+
+func BenchmarkBucketVsHeap(b *testing.B) {
+	const n, window = 4096, 60000
+	priorities := make([]int64, n)
+	r := rand.New(rand.NewPCG(1, 1))
+	for i := range priorities {
+		priorities[i] = int64(r.IntN(window))
+	}
+	b.Run("Bucket", func(b *testing.B) {
+		for range b.N {
+			bq := NewBucketPriorityQueue[int](0, 1000, window/1000)
+			for i, p := range priorities {
+				bq.Push(NewItem(i, p))
+			}
+			for bq.Len() > 0 {
+				bq.Pop()
+			}
+		}
+	})
+	b.Run("Heap", func(b *testing.B) {
+		for range b.N {
+			pq := NewPriorityQueue[int]()
+			for i, p := range priorities {
+				pq.PushItem(NewItem(i, p))
+			}
+			for pq.Len() > 0 {
+				pq.PopItem()
+			}
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package priorty_queue
+
+// ByteBoundedPriorityQueue is a BoundedPriorityQueue whose limit is the
+// items' total approximate size, as estimated for ApproxBytes, rather than
+// their count, for values whose sizes vary widely. Once the budget is
+// reached it keeps the highest priorities; Pop still returns the lowest
+// priority first. An item's size is measured when it is pushed and again
+// when it leaves, so items must not gain or lose metadata while queued.
+type ByteBoundedPriorityQueue[T any] struct {
+	maxBytes, bytes int
+	mm              MinMaxPriorityQueue[T]
+}
+
+// NewByteBoundedPriorityQueue returns an empty queue holding at most
+// maxBytes bytes of items. A negative maxBytes is treated as 0.
+func NewByteBoundedPriorityQueue[T any](maxBytes int) *ByteBoundedPriorityQueue[T] {
+	return &ByteBoundedPriorityQueue[T]{maxBytes: max(maxBytes, 0)}
+}
+
+// Push adds item to the queue, first evicting lowest-priority items until it
+// fits and returning them so the caller can clean them up. The item is
+// rejected instead (accepted is false, nothing is evicted) if it alone is
+// larger than the budget, or if making room would evict an item whose
+// priority is not lower than its own.
+func (q *ByteBoundedPriorityQueue[T]) Push(item *Item[T]) (evicted []*Item[T], accepted bool) {
+	size := approxItemBytes(item)
+	if size > q.maxBytes {
+		return nil, false
+	}
+	for q.bytes+size > q.maxBytes {
+		lowest, _ := q.mm.PeekMin()
+		if item.priority <= lowest.priority {
+			// Undo the evictions so a rejected push changes nothing.
+			for _, e := range evicted {
+				q.mm.PushItem(e)
+				q.bytes += approxItemBytes(e)
+			}
+			return nil, false
+		}
+		q.mm.PopMin()
+		q.bytes -= approxItemBytes(lowest)
+		evicted = append(evicted, lowest)
+	}
+	q.mm.PushItem(item)
+	q.bytes += size
+	return evicted, true
+}
+
+// Pop removes and returns the lowest-priority item.
+func (q *ByteBoundedPriorityQueue[T]) Pop() (*Item[T], bool) {
+	item, ok := q.mm.PopMin()
+	if ok {
+		q.bytes -= approxItemBytes(item)
+	}
+	return item, ok
+}
+
+// Peek returns the lowest-priority item without removing it.
+func (q *ByteBoundedPriorityQueue[T]) Peek() (*Item[T], bool) { return q.mm.PeekMin() }
+
+// Len returns the number of items in the queue.
+func (q *ByteBoundedPriorityQueue[T]) Len() int { return q.mm.Len() }
+
+// Bytes returns the approximate total size of the queued items.
+func (q *ByteBoundedPriorityQueue[T]) Bytes() int { return q.bytes }
+
+// MaxBytes returns the queue's byte budget.
+func (q *ByteBoundedPriorityQueue[T]) MaxBytes() int { return q.maxBytes }
@@ -0,0 +1,73 @@
+package priorty_queue
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestByteBoundedEvicts(t *testing.T) {
+	small := approxItemBytes(NewItem(strings.Repeat("s", 10), 0))
+	q := NewByteBoundedPriorityQueue[string](10 * small)
+	var evictedTotal int
+	for i := range 50 {
+		// Varying sizes, with priorities rising so each push can evict.
+		value := strings.Repeat("v", 10+i%7*5)
+		evicted, accepted := q.Push(NewItem(value, int64(i)))
+		if !accepted {
+			t.Fatalf("push %d rejected", i)
+		}
+		for _, e := range evicted {
+			if e.priority >= int64(i) {
+				t.Fatalf("push %d evicted priority %d", i, e.priority)
+			}
+		}
+		evictedTotal += len(evicted)
+		if q.Bytes() > q.MaxBytes() {
+			t.Fatalf("push %d: Bytes() = %d over budget %d", i, q.Bytes(), q.MaxBytes())
+		}
+	}
+	if evictedTotal+q.Len() != 50 {
+		t.Errorf("%d evicted + %d queued != 50 pushed", evictedTotal, q.Len())
+	}
+	var sum int
+	last := int64(-1)
+	for q.Len() > 0 {
+		item, _ := q.Pop()
+		if item.priority <= last {
+			t.Fatalf("Pop() returned %d after %d", item.priority, last)
+		}
+		last = item.priority
+		sum += approxItemBytes(item)
+	}
+	if last != 49 || q.Bytes() != 0 {
+		t.Errorf("last pop %d, Bytes() %d after drain, want 49 and 0", last, q.Bytes())
+	}
+	if sum > q.MaxBytes() {
+		t.Errorf("drained %d bytes, over budget %d", sum, q.MaxBytes())
+	}
+}
+
+func TestByteBoundedRejects(t *testing.T) {
+	small := approxItemBytes(NewItem("s", 0))
+	q := NewByteBoundedPriorityQueue[string](3 * small)
+	if _, accepted := q.Push(NewItem(strings.Repeat("x", 4*small), 100)); accepted {
+		t.Error("accepted an item larger than the whole budget")
+	}
+	for i := range 3 {
+		q.Push(NewItem("s", int64(10+i)))
+	}
+	before := q.Bytes()
+	if evicted, accepted := q.Push(NewItem("s", 5)); accepted || evicted != nil {
+		t.Errorf("low-priority push to a full queue: accepted %v, evicted %d", accepted, len(evicted))
+	}
+	// Needs two evictions, and the second item outranks it.
+	if evicted, accepted := q.Push(NewItem(strings.Repeat("s", small+1), 11)); accepted || evicted != nil {
+		t.Errorf("oversized push: accepted %v, evicted %d", accepted, len(evicted))
+	}
+	if q.Len() != 3 || q.Bytes() != before {
+		t.Errorf("rejected pushes changed the queue: Len %d, Bytes %d, want 3 and %d", q.Len(), q.Bytes(), before)
+	}
+	if top, _ := q.Peek(); top.priority != 10 {
+		t.Errorf("Peek() priority %d, want 10", top.priority)
+	}
+}
@@ -0,0 +1,43 @@
+package priorty_queue
+
+import (
+	"context"
+	"time"
+)
+
+// Clock reports the current time in milliseconds since epoch. Queues read it
+// through NowMillis so tests can substitute a fake with WithClock.
+type Clock interface {
+	NowMillis() int64
+}
+
+// RealClock is the Clock backed by the system time. It is the default.
+type RealClock struct{}
+
+// NowMillis returns time.Now in milliseconds since epoch.
+func (RealClock) NowMillis() int64 { return time.Now().UnixMilli() }
+
+// NowMillis returns the current time from the queue's clock.
+func (pq *PriorityQueue[T]) NowMillis() int64 {
+	if pq.clock == nil {
+		return RealClock{}.NowMillis()
+	}
+	return pq.clock.NowMillis()
+}
+
+// RemoveExpiredNow is RemoveExpired as of the queue's clock.
+func (pq *PriorityQueue[T]) RemoveExpiredNow() []*Item[T] {
+	return pq.RemoveExpired(pq.NowMillis())
+}
+
+// PurgeExpiredNow is PurgeExpired for items whose priority timestamp is more
+// than maxAgeMillis old by the queue's clock, i.e. with a threshold of
+// NowMillis() - maxAgeMillis.
+func (pq *PriorityQueue[T]) PurgeExpiredNow(ctx context.Context, maxAgeMillis int64, onPurged func(*Item[T])) (int, error) {
+	return pq.PurgeExpired(ctx, pq.NowMillis()-maxAgeMillis, onPurged)
+}
+
+// AgeMillis returns item's age as of the queue's clock. See Item.AgeMillis.
+func (pq *PriorityQueue[T]) AgeMillis(item *Item[T]) int64 {
+	return item.AgeMillis(pq.NowMillis())
+}
@@ -0,0 +1,69 @@
+package priorty_queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when a test sets it.
+type fakeClock struct{ now int64 }
+
+func (c *fakeClock) NowMillis() int64 { return c.now }
+
+func TestRemoveExpiredNowFollowsClock(t *testing.T) {
+	clock := &fakeClock{now: 1000}
+	pq := NewPriorityQueue(WithClock[string](clock))
+	pq.PushItem(NewItemWithExpiry("soon", 1, 1500))
+	pq.PushItem(NewItemWithExpiry("later", 2, 3000))
+	if got := pq.RemoveExpiredNow(); len(got) != 0 {
+		t.Fatalf("RemoveExpiredNow at 1000 removed %d items", len(got))
+	}
+	clock.now = 1500
+	if got := pq.RemoveExpiredNow(); len(got) != 1 || got[0].value != "soon" {
+		t.Fatalf("RemoveExpiredNow at 1500 = %v, want [soon]", got)
+	}
+	clock.now = 2999
+	if got := pq.RemoveExpiredNow(); len(got) != 0 {
+		t.Fatalf("RemoveExpiredNow at 2999 removed %d items", len(got))
+	}
+	clock.now = 5000
+	if got := pq.RemoveExpiredNow(); len(got) != 1 || got[0].value != "later" {
+		t.Fatalf("RemoveExpiredNow at 5000 = %v, want [later]", got)
+	}
+}
+
+func TestPurgeExpiredNowAndAgeFollowClock(t *testing.T) {
+	clock := &fakeClock{now: 10_000}
+	pq := NewPriorityQueue(WithClock[string](clock))
+	for _, ts := range []int64{9_000, 9_500, 9_900} {
+		pq.PushValue("event", ts)
+	}
+	if pq.NowMillis() != 10_000 {
+		t.Fatalf("NowMillis() = %d, want 10000", pq.NowMillis())
+	}
+	top, _ := pq.Peek()
+	if age := pq.AgeMillis(top); age != 1_000 {
+		t.Errorf("AgeMillis(top) = %d, want 1000", age)
+	}
+	n, err := pq.PurgeExpiredNow(context.Background(), 600, nil)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeExpiredNow(600) at 10000 = %d, %v, want 1", n, err)
+	}
+	clock.now = 10_400
+	if n, _ := pq.PurgeExpiredNow(context.Background(), 600, nil); n != 1 {
+		t.Errorf("PurgeExpiredNow(600) at 10400 purged %d, want 1", n)
+	}
+	if pq.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", pq.Len())
+	}
+}
+
+func TestRealClockIsDefault(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	before := time.Now().UnixMilli()
+	now := pq.NowMillis()
+	if now < before || now > time.Now().UnixMilli() {
+		t.Errorf("NowMillis() = %d, not the current time", now)
+	}
+}
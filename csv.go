@@ -0,0 +1,31 @@
+package priorty_queue
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes the queue to w as value,priority rows in pop order, with
+// values formatted by fmt.Sprint. It walks the heap in place rather than
+// copying it, so the queue is unchanged and no full snapshot is built. Each
+// row is flushed as it is written; on a write error WriteCSV returns the
+// number of rows fully written before it, along with the error.
+func (pq *PriorityQueue[T]) WriteCSV(w io.Writer) (int, error) {
+	cw := csv.NewWriter(w)
+	rows := 0
+	var err error
+	pq.walk(func(item *Item[T]) bool {
+		if err = cw.Write([]string{fmt.Sprint(item.value), strconv.FormatInt(item.priority, 10)}); err != nil {
+			return false
+		}
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			return false
+		}
+		rows++
+		return true
+	})
+	return rows, err
+}
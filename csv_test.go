@@ -0,0 +1,54 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("c", 30)
+	pq.PushValue("a,1", 10)
+	pq.PushValue("b", 20)
+	var buf bytes.Buffer
+	n, err := pq.WriteCSV(&buf)
+	if err != nil || n != 3 {
+		t.Fatalf("WriteCSV = %d, %v, want 3, nil", n, err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a,1", "10"}, {"b", "20"}, {"c", "30"}}
+	if !slices.EqualFunc(rows, want, slices.Equal) {
+		t.Errorf("rows %q, want %q", rows, want)
+	}
+	if pq.Len() != 3 {
+		t.Errorf("Len() = %d after WriteCSV, want 3", pq.Len())
+	}
+}
+
+// shortWriter accepts n writes and fails every one after.
+type shortWriter struct{ n int }
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestWriteCSVMidStreamError(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10 {
+		pq.PushValue(i, int64(i))
+	}
+	n, err := pq.WriteCSV(&shortWriter{n: 4})
+	if err == nil || n != 4 {
+		t.Errorf("WriteCSV = %d, %v, want 4 and an error", n, err)
+	}
+}
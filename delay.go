@@ -0,0 +1,82 @@
+package priorty_queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DelayQueue holds values until their ready time has passed. Take blocks on
+// a timer armed for the earliest ready time and is woken to re-arm it when an
+// earlier value is offered, so it never spins. Ready times are kept at
+// millisecond precision, rounded up. Use NewDelayQueue to create one.
+type DelayQueue[T any] struct {
+	mu sync.Mutex
+	pq PriorityQueue[T]
+	// wake is signalled by Offer so a waiting Take can re-check the top.
+	wake chan struct{}
+}
+
+// NewDelayQueue returns an empty DelayQueue.
+func NewDelayQueue[T any]() *DelayQueue[T] {
+	return &DelayQueue[T]{wake: make(chan struct{}, 1)}
+}
+
+// Offer adds value to the queue, to become ready at readyAt.
+func (q *DelayQueue[T]) Offer(value T, readyAt time.Time) {
+	// Round up to the next millisecond so a value is never taken early.
+	ready := readyAt.UnixMilli()
+	if readyAt.After(time.UnixMilli(ready)) {
+		ready++
+	}
+	q.mu.Lock()
+	q.pq.PushItem(NewItem(value, ready))
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Take removes and returns the item with the earliest ready time, blocking
+// until that time has passed. It returns ctx.Err() if ctx is done first.
+func (q *DelayQueue[T]) Take(ctx context.Context) (*Item[T], error) {
+	for {
+		q.mu.Lock()
+		var timer *time.Timer
+		if top, ok := q.pq.Peek(); ok {
+			wait := time.Until(top.PriorityTime())
+			if wait <= 0 {
+				item, _ := q.pq.PopItem()
+				q.mu.Unlock()
+				return item, nil
+			}
+			timer = time.NewTimer(wait)
+		}
+		q.mu.Unlock()
+
+		var ready <-chan time.Time
+		if timer != nil {
+			ready = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, ctx.Err()
+		case <-q.wake:
+		case <-ready:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// Len returns the number of values in the queue, ready or not.
+func (q *DelayQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Len()
+}
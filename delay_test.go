@@ -0,0 +1,61 @@
+package priorty_queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelayQueueWaits(t *testing.T) {
+	q := NewDelayQueue[string]()
+	start := time.Now()
+	q.Offer("later", start.Add(50*time.Millisecond))
+	item, err := q.Take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Take returned after %v, before the item was ready", elapsed)
+	}
+	if item.value != "later" || q.Len() != 0 {
+		t.Errorf("Take() = %q with Len %d", item.value, q.Len())
+	}
+}
+
+func TestDelayQueueSoonerOfferPreempts(t *testing.T) {
+	q := NewDelayQueue[string]()
+	q.Offer("slow", time.Now().Add(time.Hour))
+	got := make(chan string)
+	go func() {
+		item, err := q.Take(context.Background())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		got <- item.value
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Offer("fast", time.Now().Add(20*time.Millisecond))
+	select {
+	case v := <-got:
+		if v != "fast" {
+			t.Errorf("Take() = %q, want fast", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a sooner offer did not wake Take")
+	}
+}
+
+func TestDelayQueueCanceled(t *testing.T) {
+	q := NewDelayQueue[string]()
+	q.Offer("slow", time.Now().Add(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Take() = %v, want context.DeadlineExceeded", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d after a canceled Take, want 1", q.Len())
+	}
+}
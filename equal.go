@@ -0,0 +1,46 @@
+package priorty_queue
+
+// entry is an item's identity for Equal and Diff: its value's key (see
+// valueKey) and priority, ignoring where it sits in the heap.
+type entry struct {
+	key      any
+	priority int64
+}
+
+// Equal reports whether pq and other hold the same multiset of
+// (value, priority) pairs, regardless of array order, indices or
+// comparators. Values are matched as by pq's Contains.
+func (pq *PriorityQueue[T]) Equal(other *PriorityQueue[T]) bool {
+	if pq.Len() != other.Len() {
+		return false
+	}
+	onlyA, _ := pq.Diff(other)
+	return len(onlyA) == 0
+}
+
+// Diff returns the items of pq with no matching (value, priority) pair in
+// other, and the items of other with none in pq. Duplicates are matched one
+// for one, so a pair held twice in pq and once in other puts one of pq's
+// items in onlyA. Each slice is in array order and nil if empty.
+func (pq *PriorityQueue[T]) Diff(other *PriorityQueue[T]) (onlyA, onlyB []*Item[T]) {
+	counts := make(map[entry]int, other.Len())
+	for _, item := range other.items {
+		counts[entry{pq.valueKey(item.value), item.priority}]++
+	}
+	for _, item := range pq.items {
+		key := entry{pq.valueKey(item.value), item.priority}
+		if counts[key] == 0 {
+			onlyA = append(onlyA, item)
+			continue
+		}
+		counts[key]--
+	}
+	for _, item := range other.items {
+		key := entry{pq.valueKey(item.value), item.priority}
+		if counts[key] > 0 {
+			onlyB = append(onlyB, item)
+			counts[key]--
+		}
+	}
+	return onlyA, onlyB
+}
@@ -0,0 +1,65 @@
+package priorty_queue
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestEqualIgnoresArrayOrder(t *testing.T) {
+	a := NewPriorityQueue[string]()
+	b := NewPriorityQueue(WithComparator(MaxPriority[string]))
+	pairs := []struct {
+		value    string
+		priority int64
+	}{{"x", 1}, {"y", 2}, {"z", 3}, {"x", 1}}
+	for _, p := range pairs {
+		a.PushValue(p.value, p.priority)
+	}
+	for _, p := range slices.Backward(pairs) {
+		b.PushValue(p.value, p.priority)
+	}
+	if slices.EqualFunc(a.items, b.items, func(x, y *Item[string]) bool { return x.value == y.value }) {
+		t.Fatal("test setup: heaps share an array order")
+	}
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("Equal = false for the same contents in a different order")
+	}
+	if onlyA, onlyB := a.Diff(b); onlyA != nil || onlyB != nil {
+		t.Errorf("Diff = %v, %v, want nil, nil", onlyA, onlyB)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := NewPriorityQueue[string]()
+	b := NewPriorityQueue[string]()
+	a.PushValue("shared", 1)
+	b.PushValue("shared", 1)
+	a.PushValue("dup", 5)
+	a.PushValue("dup", 5)
+	b.PushValue("dup", 5)
+	a.PushValue("moved", 7)
+	b.PushValue("moved", 8)
+	b.PushValue("new", 9)
+
+	if a.Equal(b) {
+		t.Error("Equal = true for different contents")
+	}
+	onlyA, onlyB := a.Diff(b)
+	describe := func(items []*Item[string]) []string {
+		var s []string
+		for _, item := range items {
+			s = append(s, fmt.Sprintf("%s@%d", item.value, item.priority))
+		}
+		slices.Sort(s)
+		return s
+	}
+	wantA := describe([]*Item[string]{NewItem("dup", 5), NewItem("moved", 7)})
+	wantB := describe([]*Item[string]{NewItem("moved", 8), NewItem("new", 9)})
+	if got := describe(onlyA); !slices.Equal(got, wantA) {
+		t.Errorf("onlyA = %v, want %v", got, wantA)
+	}
+	if got := describe(onlyB); !slices.Equal(got, wantB) {
+		t.Errorf("onlyB = %v, want %v", got, wantB)
+	}
+}
@@ -0,0 +1,64 @@
+package priorty_queue
+
+import "container/heap"
+
+// expiryHeap is a min-heap, by expiry, of the queued items that have one. It
+// sits alongside the main heap so RemoveExpired can find expired items
+// without scanning. Items track their slot in it with itemExt.expIndex.
+type expiryHeap[T any] []*Item[T]
+
+func (h expiryHeap[T]) Len() int           { return len(h) }
+func (h expiryHeap[T]) Less(i, j int) bool { return h[i].ext.expiry < h[j].ext.expiry }
+
+func (h expiryHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].ext.expIndex = i
+	h[j].ext.expIndex = j
+}
+
+func (h *expiryHeap[T]) Push(x interface{}) {
+	item := x.(*Item[T])
+	item.ext.expIndex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap[T]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[0 : n-1]
+	return item
+}
+
+// expAdd records a newly queued item in the expiry heap if it expires.
+func (pq *PriorityQueue[T]) expAdd(item *Item[T]) {
+	if item.Expiry() != 0 {
+		heap.Push(&pq.expiring, item)
+	}
+}
+
+// expDelete drops an item leaving the queue from the expiry heap, if it is
+// there.
+func (pq *PriorityQueue[T]) expDelete(item *Item[T]) {
+	if item.Expiry() == 0 {
+		return
+	}
+	if i := item.ext.expIndex; i < len(pq.expiring) && pq.expiring[i] == item {
+		heap.Remove(&pq.expiring, i)
+	}
+}
+
+// expRebuild rebuilds the expiry heap from the queued items in O(n), after
+// the item set was replaced wholesale.
+func (pq *PriorityQueue[T]) expRebuild() {
+	clear(pq.expiring)
+	pq.expiring = pq.expiring[:0]
+	for _, item := range pq.items {
+		if item.Expiry() != 0 {
+			item.ext.expIndex = len(pq.expiring)
+			pq.expiring = append(pq.expiring, item)
+		}
+	}
+	heap.Init(&pq.expiring)
+}
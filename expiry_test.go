@@ -0,0 +1,128 @@
+package priorty_queue
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// checkExpiryIndex fails the test unless pq.expiring holds exactly the queued
+// items that expire, as a valid heap with correct expIndex fields.
+func checkExpiryIndex[T any](t *testing.T, pq *PriorityQueue[T]) {
+	t.Helper()
+	want := 0
+	for _, item := range pq.items {
+		if item.Expiry() == 0 {
+			continue
+		}
+		want++
+		if i := item.ext.expIndex; i >= len(pq.expiring) || pq.expiring[i] != item {
+			t.Fatalf("item %v has expIndex %d, not its expiry slot", item.value, i)
+		}
+	}
+	if len(pq.expiring) != want {
+		t.Fatalf("expiry heap holds %d items, %d queued items expire", len(pq.expiring), want)
+	}
+	for i, item := range pq.expiring {
+		if !pq.owns(item) {
+			t.Fatalf("expiry heap slot %d holds %v, which is not queued", i, item.value)
+		}
+		if parent := (i - 1) / 2; i > 0 && item.ext.expiry < pq.expiring[parent].ext.expiry {
+			t.Fatalf("expiry heap slot %d expires before its parent", i)
+		}
+	}
+}
+
+func TestExpiryIndexRandomized(t *testing.T) {
+	r := rand.New(rand.NewPCG(9, 10))
+	pq := NewPriorityQueue[int]()
+	var now int64
+	next := 0
+	randomItem := func() *Item[int] { return pq.items[r.IntN(len(pq.items))] }
+	for op := range 20_000 {
+		switch k := r.IntN(12); {
+		case k < 4:
+			if r.IntN(3) == 0 {
+				pq.PushItem(NewItem(next, r.Int64N(1000)))
+			} else {
+				pq.PushItem(NewItemWithExpiry(next, r.Int64N(1000), now+1+r.Int64N(500)))
+			}
+			next++
+		case k == 4:
+			pq.PopItem()
+		case k == 5 && pq.Len() > 0:
+			pq.Remove(randomItem())
+		case k == 6 && pq.Len() > 0:
+			pq.Update(randomItem(), r.Int64N(1000))
+		case k == 7:
+			now += r.Int64N(50)
+			for _, item := range pq.RemoveExpired(now) {
+				if item.Expiry() > now || item.index != -1 {
+					t.Fatalf("op %d: RemoveExpired(%d) returned expiry %d, index %d", op, now, item.Expiry(), item.index)
+				}
+			}
+			for _, item := range pq.items {
+				if e := item.Expiry(); e != 0 && e <= now {
+					t.Fatalf("op %d: expired item %v left queued", op, item.value)
+				}
+			}
+		case k == 8:
+			pq.RemoveWhere(func(item *Item[int]) bool { return item.value%7 == 0 })
+		case k == 9:
+			pq.PopExpired(r.Int64N(100))
+		case k == 10 && pq.Len() > 20:
+			pq.TrimToSmallest(pq.Len() - 5)
+		case k == 11:
+			other := NewPriorityQueue[int]()
+			other.PushItem(NewItemWithExpiry(next, r.Int64N(1000), now+1+r.Int64N(500)))
+			next++
+			pq.Merge(other)
+			checkExpiryIndex(t, other)
+		}
+		checkExpiryIndex(t, pq)
+	}
+	mustValidate(t, pq)
+}
+
+func BenchmarkRemoveExpired(b *testing.B) {
+	const n = 1_000_000
+	const now = 1_000
+	for _, bc := range []struct {
+		name    string
+		percent int
+		scan    bool
+	}{
+		{"0%", 0, false},
+		{"5%", 5, false},
+		{"0%/scan", 0, true},
+		{"5%/scan", 5, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			pq := NewPriorityQueue(WithCapacity[int](n))
+			for i := range n {
+				expiry := int64(now + 1 + i%1000)
+				if i%100 < bc.percent {
+					expiry = now
+				}
+				pq.PushItem(NewItemWithExpiry(i, int64(i*7919%n), expiry))
+			}
+			b.ResetTimer()
+			for range b.N {
+				var expired []*Item[int]
+				if bc.scan {
+					// The O(n) pass RemoveExpired used to make.
+					expired = pq.RemoveWhere(func(item *Item[int]) bool {
+						e := item.Expiry()
+						return e != 0 && e <= now
+					})
+				} else {
+					expired = pq.RemoveExpired(now)
+				}
+				b.StopTimer()
+				for _, item := range expired {
+					pq.Requeue(item, item.priority)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
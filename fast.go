@@ -0,0 +1,99 @@
+package priorty_queue
+
+import "slices"
+
+// FastPush is PushItem with a hand-written sift: instead of swapping the new
+// item up one level at a time, it holds the item aside, shifts each parent
+// it outranks down into the hole, and writes the item once into its final
+// slot. That halves the pointer and index writes per level compared with
+// container/heap's Swap-based path. The queue's order, indices and hooks are
+// the same either way, so FastPush and FastPop mix freely with the standard
+// methods.
+func (pq *PriorityQueue[T]) FastPush(item *Item[T]) bool {
+	if !pq.admits(item) {
+		return false
+	}
+	defer pq.rethrow("FastPush", item)
+	item.priority = pq.jittered(item)
+	pq.Push(item)
+	pq.siftUp(len(pq.items) - 1)
+	return true
+}
+
+// FastPop is PopItem using the same hole-based sift as FastPush. ok is false
+// when the queue is empty.
+func (pq *PriorityQueue[T]) FastPop() (*Item[T], bool) {
+	n := len(pq.items) - 1
+	if n < 0 {
+		return nil, false
+	}
+	top := pq.items[0]
+	defer pq.rethrow("FastPop", top)
+	if n > 0 {
+		pq.siftDown(pq.items[n], n)
+	}
+	// Pop takes the item from the end and does the bookkeeping.
+	pq.items[n] = top
+	return pq.Pop().(*Item[T]), true
+}
+
+// siftUp moves the item at slot i up to its place, shifting outranked
+// parents down rather than swapping.
+func (pq *PriorityQueue[T]) siftUp(i int) {
+	item := pq.items[i]
+	for i > 0 {
+		parent := (i - 1) / 2
+		p := pq.items[parent]
+		if !pq.before(item, p) {
+			break
+		}
+		pq.items[i] = p
+		p.index = i
+		i = parent
+	}
+	pq.items[i] = item
+	item.index = i
+}
+
+// siftDown places item into the hole at the root of the heap formed by the
+// first n slots, shifting higher-ranked children up rather than swapping.
+func (pq *PriorityQueue[T]) siftDown(item *Item[T], n int) {
+	i := 0
+	for {
+		child := 2*i + 1
+		if child >= n {
+			break
+		}
+		if right := child + 1; right < n && pq.before(pq.items[right], pq.items[child]) {
+			child = right
+		}
+		c := pq.items[child]
+		if !pq.before(c, item) {
+			break
+		}
+		pq.items[i] = c
+		c.index = i
+		i = child
+	}
+	pq.items[i] = item
+	item.index = i
+}
+
+// SortInPlace heapsorts the backing slice into pop order (ascending priority
+// for the default min-heap) without allocating, and renumbers every item's
+// index to its new slot, for a one-shot export of the array through All or
+// similar. A slice in pop order is itself a valid heap, so the queue stays
+// usable afterwards; Validate confirms this.
+func (pq *PriorityQueue[T]) SortInPlace() {
+	// Each pass moves the top of the shrinking heap to just past its end,
+	// leaving the slice in reverse pop order.
+	for end := len(pq.items) - 1; end > 0; end-- {
+		top := pq.items[0]
+		pq.siftDown(pq.items[end], end)
+		pq.items[end] = top
+	}
+	slices.Reverse(pq.items)
+	for i, item := range pq.items {
+		item.index = i
+	}
+}
@@ -0,0 +1,96 @@
+package priorty_queue
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFastMatchesHeap(t *testing.T) {
+	fast := NewPriorityQueue[int]()
+	std := NewPriorityQueue[int]()
+	for i := range 500 {
+		p := int64(i * 7919 % 97)
+		fast.FastPush(NewItem(i, p))
+		std.PushItem(NewItem(i, p))
+		if i%3 == 2 {
+			f, _ := fast.FastPop()
+			s, _ := std.PopItem()
+			if f.value != s.value || f.index != -1 {
+				t.Fatalf("step %d: FastPop() = %v (index %d), PopItem() = %v", i, f.value, f.index, s.value)
+			}
+		}
+	}
+	mustValidate(t, fast)
+	for i, item := range fast.items {
+		if item.index != i {
+			t.Fatalf("item at slot %d has index %d", i, item.index)
+		}
+	}
+	var got []int
+	for fast.Len() > 0 {
+		item, _ := fast.FastPop()
+		got = append(got, item.value)
+	}
+	if want := popValues(std); !slices.Equal(got, want) {
+		t.Errorf("FastPop order %v, want %v", got, want)
+	}
+}
+
+func TestSortInPlace(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 100 {
+		pq.PushValue(i, int64(i*37%100))
+	}
+	pq.SortInPlace()
+	mustValidate(t, pq)
+	for i, item := range pq.items {
+		if item.priority != int64(i) || item.index != i {
+			t.Fatalf("slot %d holds priority %d, index %d", i, item.priority, item.index)
+		}
+	}
+
+	pq.Init()
+	mustValidate(t, pq)
+	pq.PushValue(-1, 50)
+	pq.Update(pq.items[99], -5)
+	pq.Remove(pq.items[10])
+	mustValidate(t, pq)
+	if top, _ := pq.PopItem(); top.priority != -5 {
+		t.Errorf("PopItem() after Init = priority %d, want -5", top.priority)
+	}
+	last := int64(math.MinInt64)
+	for pq.Len() > 0 {
+		item, _ := pq.PopItem()
+		if item.priority < last {
+			t.Fatalf("popped %d after %d", item.priority, last)
+		}
+		last = item.priority
+	}
+}
+
+// benchmarkPushPop keeps a queue of 1024 items and, per op, pops the top and
+// pushes it back at a new priority.
+func benchmarkPushPop(b *testing.B, push func(*PriorityQueue[int], *Item[int]) bool,
+	pop func(*PriorityQueue[int]) (*Item[int], bool)) {
+	pq := NewPriorityQueue[int]()
+	for i := range 1024 {
+		push(pq, NewItem(i, int64(i*7919%1024)))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		item, _ := pop(pq)
+		item.priority += int64(i * 7919 % 1024)
+		push(pq, item)
+	}
+}
+
+func BenchmarkFastPushPop(b *testing.B) {
+	b.Run("container-heap", func(b *testing.B) {
+		benchmarkPushPop(b, (*PriorityQueue[int]).PushItem, (*PriorityQueue[int]).PopItem)
+	})
+	b.Run("hole-sift", func(b *testing.B) {
+		benchmarkPushPop(b, (*PriorityQueue[int]).FastPush, (*PriorityQueue[int]).FastPop)
+	})
+}
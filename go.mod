@@ -1,3 +1,3 @@
 module priorty_queue
 
-go 1.14
+go 1.23
@@ -0,0 +1,67 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// gobItem is the gob wire form of an Item. The index is never transmitted; it
+// is recomputed by the receiving queue.
+type gobItem[T any] struct {
+	Value    T
+	Priority int64
+}
+
+// GobEncode encodes the item's value and priority.
+func (it *Item[T]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gobItem[T]{it.value, it.priority}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes an item's value and priority. The index is left unset
+// until the item is pushed onto a queue.
+func (it *Item[T]) GobDecode(data []byte) error {
+	var g gobItem[T]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return err
+	}
+	it.value, it.priority = g.Value, g.Priority
+	return nil
+}
+
+// GobEncode encodes the queue's items in pop order.
+func (pq *PriorityQueue[T]) GobEncode() ([]byte, error) {
+	items := make([]gobItem[T], 0, pq.Len())
+	pq.walk(func(item *Item[T]) bool {
+		items = append(items, gobItem[T]{item.value, item.priority})
+		return true
+	})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(items); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the queue's contents with the decoded items, assigning
+// fresh indices and rebuilding the heap. The queue's comparator is kept. More
+// items than the queue's size limit is an error wrapping ErrQueueFull, and the
+// queue is left unchanged.
+func (pq *PriorityQueue[T]) GobDecode(data []byte) error {
+	var records []gobItem[T]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&records); err != nil {
+		return err
+	}
+	items := make([]*Item[T], len(records))
+	for i, r := range records {
+		items[i] = NewItem(r.Value, r.Priority)
+	}
+	if err := pq.loadBounded(items); err != nil {
+		return fmt.Errorf("GobDecode: %w", err)
+	}
+	return nil
+}
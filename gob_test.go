@@ -0,0 +1,35 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestGobRoundTrip(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i, v := range []string{"e", "b", "a", "d", "c", "b"} {
+		pq.PushValue(v, int64(i*3%4))
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pq); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewPriorityQueue[string]()
+	if err := gob.NewDecoder(&buf).Decode(restored); err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, restored)
+	if got, want := restored.PopSequence(), pq.PopSequence(); got != want {
+		t.Errorf("decoded pop order:\n%s\nwant:\n%s", got, want)
+	}
+
+	var item Item[string]
+	buf.Reset()
+	if err := gob.NewEncoder(&buf).Encode(NewItem("x", 7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewDecoder(&buf).Decode(&item); err != nil || item.value != "x" || item.priority != 7 {
+		t.Errorf("decoded item %q/%d, err %v", item.value, item.priority, err)
+	}
+}
@@ -0,0 +1,19 @@
+package priorty_queue
+
+// PriorityHistogram counts the queued items per bucket of bucketMillis
+// milliseconds, keyed by floor(priority / bucketMillis). The queue is not
+// modified. It returns nil if bucketMillis is not positive.
+func (pq *PriorityQueue[T]) PriorityHistogram(bucketMillis int64) map[int64]int {
+	if bucketMillis <= 0 {
+		return nil
+	}
+	hist := make(map[int64]int)
+	for _, item := range pq.items {
+		bucket := item.priority / bucketMillis
+		if item.priority%bucketMillis < 0 {
+			bucket--
+		}
+		hist[bucket]++
+	}
+	return hist
+}
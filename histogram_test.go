@@ -0,0 +1,20 @@
+package priorty_queue
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestPriorityHistogram(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i, p := range []int64{0, 5, 9, 10, 15, 42, -1, -10} {
+		pq.PushValue(i, p)
+	}
+	want := map[int64]int{0: 3, 1: 2, 4: 1, -1: 2}
+	if got := pq.PriorityHistogram(10); !maps.Equal(got, want) {
+		t.Errorf("PriorityHistogram(10) = %v, want %v", got, want)
+	}
+	if got := pq.PriorityHistogram(0); got != nil {
+		t.Errorf("PriorityHistogram(0) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,98 @@
+package priorty_queue
+
+import (
+	"slices"
+	"unsafe"
+)
+
+// valueIndex maps values to the queued items holding them, for the by-value
+// methods such as Contains and UpdateByValue. It hides the key type, so a
+// queue over a non-comparable payload can still be indexed by a comparable
+// part of it; see WithValueIndex and WithValueIndexBy.
+type valueIndex[T any] interface {
+	// add records item under its value's key.
+	add(item *Item[T])
+	// remove drops item from the index.
+	remove(item *Item[T])
+	// get returns the items holding value's key, in push order.
+	get(value T) []*Item[T]
+	// key returns the comparable identity value is matched by.
+	key(value T) any
+	// clear empties the index, keeping its storage.
+	clear()
+	// fresh returns an empty index with the same key, sized for n items.
+	fresh(n int) valueIndex[T]
+	// remap returns a copy of the index with every item replaced by
+	// copyOf(item), for Clone.
+	remap(copyOf func(*Item[T]) *Item[T]) valueIndex[T]
+	// entryBytes estimates the memory one indexed item costs, for
+	// ApproxBytes.
+	entryBytes() int
+}
+
+// keyedIndex is the valueIndex for key type K.
+type keyedIndex[T any, K comparable] struct {
+	keyOf func(T) K
+	items map[K][]*Item[T]
+}
+
+func newKeyedIndex[T any, K comparable](keyOf func(T) K, n int) *keyedIndex[T, K] {
+	return &keyedIndex[T, K]{keyOf: keyOf, items: make(map[K][]*Item[T], n)}
+}
+
+func (x *keyedIndex[T, K]) add(item *Item[T]) {
+	k := x.keyOf(item.value)
+	items := x.items[k]
+	// Items are nearly always added in push order; one put back after it
+	// left the queue goes before any later duplicates.
+	i := len(items)
+	for i > 0 && items[i-1].seq > item.seq {
+		i--
+	}
+	x.items[k] = slices.Insert(items, i, item)
+}
+
+func (x *keyedIndex[T, K]) remove(item *Item[T]) {
+	k := x.keyOf(item.value)
+	items := x.items[k]
+	for i, it := range items {
+		if it == item {
+			items = append(items[:i], items[i+1:]...)
+			break
+		}
+	}
+	if len(items) == 0 {
+		delete(x.items, k)
+	} else {
+		x.items[k] = items
+	}
+}
+
+func (x *keyedIndex[T, K]) get(value T) []*Item[T] { return x.items[x.keyOf(value)] }
+
+func (x *keyedIndex[T, K]) key(value T) any { return x.keyOf(value) }
+
+func (x *keyedIndex[T, K]) clear() { clear(x.items) }
+
+func (x *keyedIndex[T, K]) fresh(n int) valueIndex[T] { return newKeyedIndex(x.keyOf, n) }
+
+func (x *keyedIndex[T, K]) remap(copyOf func(*Item[T]) *Item[T]) valueIndex[T] {
+	c := newKeyedIndex(x.keyOf, len(x.items))
+	for k, items := range x.items {
+		copies := make([]*Item[T], len(items))
+		for i, item := range items {
+			copies[i] = copyOf(item)
+		}
+		c.items[k] = copies
+	}
+	return c
+}
+
+func (x *keyedIndex[T, K]) entryBytes() int {
+	var k K
+	return int(unsafe.Sizeof(k)) + int(unsafe.Sizeof([]*Item[T]{})) + mapEntryOverhead
+}
+
+// identity is the key function of WithValueIndex: a comparable value is its
+// own key.
+func identity[T comparable](v T) T { return v }
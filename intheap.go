@@ -0,0 +1,47 @@
+package priorty_queue
+
+import "container/heap"
+
+// IntHeap is a min-heap of bare int64 priorities, for uses such as
+// scheduling ticks that need no payload. It stores 8 bytes per entry instead
+// of a pointer to a full Item, so large heaps are much smaller and put no
+// load on the garbage collector. The zero value is an empty heap.
+//
+// IntHeap implements heap.Interface; PushInt, PopInt and Peek are the
+// convenient entry points.
+type IntHeap []int64
+
+func (h IntHeap) Len() int           { return len(h) }
+func (h IntHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h IntHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *IntHeap) Push(x interface{}) { *h = append(*h, x.(int64)) }
+
+func (h *IntHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// PushInt adds priority to the heap.
+func (h *IntHeap) PushInt(priority int64) { heap.Push(h, priority) }
+
+// PopInt removes and returns the lowest priority. ok is false when the heap
+// is empty.
+func (h *IntHeap) PopInt() (int64, bool) {
+	if len(*h) == 0 {
+		return 0, false
+	}
+	return heap.Pop(h).(int64), true
+}
+
+// Peek returns the lowest priority without removing it. ok is false when the
+// heap is empty.
+func (h IntHeap) Peek() (int64, bool) {
+	if len(h) == 0 {
+		return 0, false
+	}
+	return h[0], true
+}
@@ -0,0 +1,67 @@
+package priorty_queue
+
+import (
+	"container/heap"
+	"slices"
+	"testing"
+)
+
+func TestIntHeap(t *testing.T) {
+	var h IntHeap
+	if _, ok := h.PopInt(); ok {
+		t.Fatal("PopInt() on empty = ok")
+	}
+	for _, p := range []int64{5, -1, 3, 0, 9, 3} {
+		h.PushInt(p)
+	}
+	if p, ok := h.Peek(); !ok || p != -1 {
+		t.Fatalf("Peek() = %d, %v", p, ok)
+	}
+	var got []int64
+	for {
+		p, ok := h.PopInt()
+		if !ok {
+			break
+		}
+		got = append(got, p)
+	}
+	if want := []int64{-1, 0, 3, 3, 5, 9}; !slices.Equal(got, want) {
+		t.Errorf("popped %v, want %v", got, want)
+	}
+}
+
+// BenchmarkFootprint builds a 1M-entry heap per op; B/op compares the memory
+// each representation needs.
+func BenchmarkFootprint(b *testing.B) {
+	const n = 1 << 20
+	b.Run("IntHeap", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			h := make(IntHeap, n)
+			for i := range h {
+				h[i] = int64(n - i)
+			}
+			heap.Init(&h)
+		}
+	})
+	b.Run("PriorityQueue[struct{}]", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			items := make([]*Item[struct{}], n)
+			for i := range items {
+				items[i] = NewItem(struct{}{}, int64(n-i))
+			}
+			NewPriorityQueueFromItems(items...)
+		}
+	})
+	b.Run("PriorityQueue[string]", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			items := make([]*Item[string], n)
+			for i := range items {
+				items[i] = NewItem("", int64(n-i))
+			}
+			NewPriorityQueueFromItems(items...)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package priorty_queue
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonItem is the serialized form of an Item. The index is not included: it
+// only has meaning inside the queue that holds the item.
+type jsonItem[T any] struct {
+	Value    T     `json:"value"`
+	Priority int64 `json:"priority"`
+}
+
+// MarshalJSON encodes the item's value and priority.
+func (it *Item[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonItem[T]{Value: it.value, Priority: it.priority})
+}
+
+// UnmarshalJSON decodes an item's value and priority. The index is left unset
+// until the item is pushed onto a queue.
+func (it *Item[T]) UnmarshalJSON(data []byte) error {
+	var j jsonItem[T]
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	it.value, it.priority = j.Value, j.Priority
+	return nil
+}
+
+// MarshalJSON encodes the queue as an array of items in pop order.
+func (pq *PriorityQueue[T]) MarshalJSON() ([]byte, error) {
+	items := make([]*Item[T], 0, pq.Len())
+	pq.walk(func(item *Item[T]) bool {
+		items = append(items, item)
+		return true
+	})
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON replaces the queue's contents with the decoded items and
+// rebuilds the heap with heap.Init. The queue's comparator is kept. A null
+// item is an error, as is exceeding the queue's size limit (see
+// ErrQueueFull); either way the queue is left unchanged.
+func (pq *PriorityQueue[T]) UnmarshalJSON(data []byte) error {
+	var items []*Item[T]
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for i, item := range items {
+		if item == nil {
+			return fmt.Errorf("UnmarshalJSON: item %d is null", i)
+		}
+	}
+	if err := pq.loadBounded(items); err != nil {
+		return fmt.Errorf("UnmarshalJSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package priorty_queue
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i := range 50 {
+		pq.PushValue(string(rune('A'+i%26))+string(rune('a'+i/26)), int64((i*37)%50))
+	}
+	data, err := json.Marshal(pq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored PriorityQueue[string]
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, &restored)
+	if got, want := restored.PopSequence(), pq.PopSequence(); got != want {
+		t.Errorf("restored pop order:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnmarshalJSONNullItem(t *testing.T) {
+	var pq PriorityQueue[string]
+	pq.PushValue("kept", 1)
+	if err := json.Unmarshal([]byte(`[{"value":"a","priority":1},null]`), &pq); err == nil {
+		t.Fatal("Unmarshal of a null item succeeded")
+	}
+	if got := pq.SortedValues(); !slices.Equal(got, []string{"kept"}) {
+		t.Errorf("queue after failed Unmarshal holds %v, want [kept]", got)
+	}
+}
@@ -0,0 +1,110 @@
+package priorty_queue
+
+// DefaultLazyCompactThreshold is the deleted fraction at which a
+// LazyPriorityQueue compacts when it is given no valid threshold.
+const DefaultLazyCompactThreshold = 0.5
+
+// LazyPriorityQueue is a PriorityQueue whose Remove only marks the item
+// deleted, in O(1), instead of fixing the heap. Deleted items are skipped
+// when they reach the top and are swept out in one O(n) pass once they make
+// up more than the compaction threshold of the heap, so removing many
+// scattered items from a huge queue costs far less than one heap.Remove
+// each. Use NewLazyPriorityQueue to create one.
+//
+// Any OnRemove hook runs when a deleted item is physically dropped, not when
+// Remove marks it.
+type LazyPriorityQueue[T any] struct {
+	pq PriorityQueue[T]
+	// deleted holds the marked items still in pq.
+	deleted   map[*Item[T]]struct{}
+	threshold float64
+}
+
+// NewLazyPriorityQueue returns an empty queue that compacts when more than
+// threshold (a fraction in (0, 1]) of its items are deleted. Any other
+// threshold selects DefaultLazyCompactThreshold. opts configure the
+// underlying queue as for NewPriorityQueue.
+func NewLazyPriorityQueue[T any](threshold float64, opts ...Option[T]) *LazyPriorityQueue[T] {
+	if !(threshold > 0 && threshold <= 1) {
+		threshold = DefaultLazyCompactThreshold
+	}
+	return &LazyPriorityQueue[T]{pq: *NewPriorityQueue(opts...), threshold: threshold}
+}
+
+// Len returns the number of items that have not been deleted.
+func (q *LazyPriorityQueue[T]) Len() int { return q.pq.Len() - len(q.deleted) }
+
+// Push adds item to the queue. Like PushItem, it returns false if the queue
+// is full; deleted items still awaiting compaction count towards the limit.
+func (q *LazyPriorityQueue[T]) Push(item *Item[T]) bool {
+	return q.pq.PushItem(item)
+}
+
+// Pop removes and returns the top item that has not been deleted, dropping
+// any deleted items above it. ok is false when no live items remain.
+func (q *LazyPriorityQueue[T]) Pop() (*Item[T], bool) {
+	q.skipDeleted()
+	return q.pq.PopItem()
+}
+
+// Peek returns the top item that has not been deleted without removing it,
+// dropping any deleted items above it. ok is false when no live items remain.
+func (q *LazyPriorityQueue[T]) Peek() (*Item[T], bool) {
+	q.skipDeleted()
+	return q.pq.Peek()
+}
+
+// Update changes the priority of item. It returns false if item is not in
+// the queue or has been deleted.
+func (q *LazyPriorityQueue[T]) Update(item *Item[T], priority int64) bool {
+	if _, ok := q.deleted[item]; ok {
+		return false
+	}
+	return q.pq.Update(item, priority)
+}
+
+// Remove marks item deleted, compacting the queue if that takes the deleted
+// fraction over the threshold. It returns false if item is not in the queue
+// or is already deleted.
+func (q *LazyPriorityQueue[T]) Remove(item *Item[T]) bool {
+	if _, ok := q.deleted[item]; ok || !q.pq.owns(item) {
+		return false
+	}
+	if q.deleted == nil {
+		q.deleted = make(map[*Item[T]]struct{})
+	}
+	q.deleted[item] = struct{}{}
+	if float64(len(q.deleted)) > q.threshold*float64(q.pq.Len()) {
+		q.Compact()
+	}
+	return true
+}
+
+// Compact drops every deleted item in one pass, re-heapifies, and releases
+// the spare capacity they occupied.
+func (q *LazyPriorityQueue[T]) Compact() {
+	if len(q.deleted) == 0 {
+		return
+	}
+	q.pq.extract(func(item *Item[T]) bool {
+		_, ok := q.deleted[item]
+		return ok
+	})
+	clear(q.deleted)
+	q.pq.ShrinkToFit()
+}
+
+// skipDeleted pops deleted items off the top until a live one is there.
+func (q *LazyPriorityQueue[T]) skipDeleted() {
+	for len(q.deleted) > 0 {
+		top, ok := q.pq.Peek()
+		if !ok {
+			return
+		}
+		if _, ok := q.deleted[top]; !ok {
+			return
+		}
+		q.pq.PopItem()
+		delete(q.deleted, top)
+	}
+}
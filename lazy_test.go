@@ -0,0 +1,81 @@
+package priorty_queue
+
+import "testing"
+
+func TestLazyRemoveHalf(t *testing.T) {
+	q := NewLazyPriorityQueue[int](0.5)
+	items := make([]*Item[int], 100)
+	for i := range items {
+		items[i] = NewItem(i, int64(i))
+		q.Push(items[i])
+	}
+	for i := 0; i < len(items); i += 2 {
+		if !q.Remove(items[i]) {
+			t.Fatalf("Remove(%d) = false", i)
+		}
+	}
+	if q.Remove(items[0]) {
+		t.Error("second Remove of the same item = true")
+	}
+	if q.Update(items[0], 5) {
+		t.Error("Update of a deleted item = true")
+	}
+	if q.Len() != 50 {
+		t.Fatalf("Len() = %d, want 50", q.Len())
+	}
+	// Exactly half deleted does not exceed the threshold.
+	if q.pq.Len() != 100 {
+		t.Fatalf("physical length %d, want 100 before compaction", q.pq.Len())
+	}
+	if top, _ := q.Peek(); top.value != 1 {
+		t.Errorf("Peek() = %d, want 1", top.value)
+	}
+	for want := 1; want < 100; want += 2 {
+		item, ok := q.Pop()
+		if !ok || item.value != want {
+			t.Fatalf("Pop() = %v, %v, want %d", item, ok, want)
+		}
+	}
+	if item, ok := q.Pop(); ok {
+		t.Errorf("Pop() on a drained queue = %d", item.value)
+	}
+	if len(q.deleted) != 0 {
+		t.Errorf("%d deleted items left after draining", len(q.deleted))
+	}
+}
+
+func TestLazyCompactsAtThreshold(t *testing.T) {
+	var dropped int
+	q := NewLazyPriorityQueue(0.25, WithOnRemove(func(*Item[int]) { dropped++ }))
+	items := make([]*Item[int], 1000)
+	for i := range items {
+		items[i] = NewItem(i, int64(i))
+		q.Push(items[i])
+	}
+	capBefore := q.pq.Cap()
+	for i := range 250 {
+		q.Remove(items[i*4])
+	}
+	if q.pq.Len() != 1000 || dropped != 0 {
+		t.Fatalf("compacted early: physical length %d, %d dropped", q.pq.Len(), dropped)
+	}
+	q.Remove(items[1])
+	if q.pq.Len() != 749 || q.Len() != 749 {
+		t.Fatalf("after compaction physical %d, logical %d, want 749", q.pq.Len(), q.Len())
+	}
+	if dropped != 251 {
+		t.Errorf("OnRemove ran %d times, want 251", dropped)
+	}
+	if q.pq.Cap() >= capBefore {
+		t.Errorf("Cap() = %d after compaction, want below %d", q.pq.Cap(), capBefore)
+	}
+	mustValidate(t, &q.pq)
+}
+
+func TestLazyInvalidThreshold(t *testing.T) {
+	for _, threshold := range []float64{0, -1, 1.5} {
+		if q := NewLazyPriorityQueue[int](threshold); q.threshold != DefaultLazyCompactThreshold {
+			t.Errorf("threshold %v became %v, want the default", threshold, q.threshold)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package priorty_queue
+
+// SetMeta attaches the annotation k=v to the item, replacing any previous
+// value for k. Metadata travels with the item through pushes, updates and
+// pops but never affects its position in a queue.
+func (it *Item[T]) SetMeta(k, v string) {
+	ext := it.extended()
+	if ext.meta == nil {
+		ext.meta = make(map[string]string)
+	}
+	ext.meta[k] = v
+}
+
+// Meta returns the annotation stored under k. ok is false if none was set.
+func (it *Item[T]) Meta(k string) (string, bool) {
+	if it.ext == nil {
+		return "", false
+	}
+	v, ok := it.ext.meta[k]
+	return v, ok
+}
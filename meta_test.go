@@ -0,0 +1,63 @@
+package priorty_queue
+
+import "testing"
+
+func TestMetaSurvivesHeapOperations(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	tagged := NewItem("tagged", 50)
+	if _, ok := tagged.Meta("tenant"); ok {
+		t.Fatal("Meta on a fresh item = ok")
+	}
+	tagged.SetMeta("tenant", "acme")
+	tagged.SetMeta("host", "db1")
+	tagged.SetMeta("host", "db2")
+	pq.PushItem(tagged)
+	for i := range 20 {
+		pq.PushValue("filler", int64(i*5))
+	}
+
+	pq.Update(tagged, -1)
+	if top, _ := pq.Peek(); top != tagged {
+		t.Fatalf("Peek() = %v, want the reprioritized item", top.value)
+	}
+	pq.Update(tagged, 99)
+	pq.Adjust(tagged, -40)
+	mustValidate(t, pq)
+
+	var popped *Item[string]
+	for {
+		item, ok := pq.PopItem()
+		if !ok {
+			break
+		}
+		if item == tagged {
+			popped = item
+		}
+	}
+	if popped == nil {
+		t.Fatal("tagged item never popped")
+	}
+	if v, ok := popped.Meta("tenant"); !ok || v != "acme" {
+		t.Errorf("Meta(tenant) = %q, %v, want acme, true", v, ok)
+	}
+	if v, ok := popped.Meta("host"); !ok || v != "db2" {
+		t.Errorf("Meta(host) = %q, %v, want db2, true", v, ok)
+	}
+	if _, ok := popped.Meta("missing"); ok {
+		t.Error("Meta(missing) = ok")
+	}
+}
+
+func TestMetaIgnoredByOrdering(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for _, v := range []string{"a", "b", "c"} {
+		item := NewItem(v, 1)
+		if v == "b" {
+			item.SetMeta("tenant", "zzz")
+		}
+		pq.PushItem(item)
+	}
+	if got := popValues(pq); got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("pop order %v, want [a b c]", got)
+	}
+}
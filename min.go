@@ -0,0 +1,163 @@
+package priorty_queue
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// Min returns the item with the lowest raw priority, whatever the queue's
+// comparator. For a min-heap that is the top, but for a MaxPriority queue it
+// sits somewhere among the leaves. ok is false when the queue is empty.
+//
+// Under MinPriority (the default) and MinPriorityThenValue the lowest
+// priority is always the top, so Min is O(1) with no bookkeeping. Under any
+// other comparator the minimum is cached: pushes and updates keep the cache
+// current with one comparison each, so Min is O(1), but once the cached item
+// itself is popped, removed or raised, or after a bulk operation such as
+// Init, the next Min rescans the queue in O(n).
+func (pq *PriorityQueue[T]) Min() (*Item[T], bool) {
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	if pq.topOrder() == orderMin {
+		return pq.items[0], true
+	}
+	if pq.minItem == nil {
+		pq.minItem = pq.items[0]
+		for _, item := range pq.items[1:] {
+			if item.priority < pq.minItem.priority {
+				pq.minItem = item
+			}
+		}
+	}
+	return pq.minItem, true
+}
+
+// Max returns the item with the highest raw priority, which for the default
+// min-heap is buried among the leaves. Under MaxPriority it is the top and
+// Max is O(1). Otherwise it is cached the same way as Min, costing one more
+// comparison per push or update, and rescans in O(n) after the cached item
+// leaves or is lowered; in a min-heap that happens only when the largest
+// item goes, not on every Pop. ok is false when the queue is empty.
+func (pq *PriorityQueue[T]) Max() (*Item[T], bool) {
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	if pq.topOrder() == orderMax {
+		return pq.items[0], true
+	}
+	if pq.maxItem == nil {
+		pq.maxItem = pq.items[0]
+		for _, item := range pq.items[1:] {
+			if item.priority > pq.maxItem.priority {
+				pq.maxItem = item
+			}
+		}
+	}
+	return pq.maxItem, true
+}
+
+// heapOrder is which raw-priority extreme a comparator keeps at the top of
+// the heap, when the comparator is one the package recognizes.
+type heapOrder int8
+
+const (
+	orderUnknown heapOrder = iota
+	orderMin
+	orderMax
+)
+
+// topOrder returns the heap order of the queue's comparator.
+func (pq *PriorityQueue[T]) topOrder() heapOrder {
+	if pq.less == nil {
+		return orderMin
+	}
+	return pq.order
+}
+
+// Function values for different instantiations of a generic function never
+// compare equal, but the runtime names them all alike, with the type
+// arguments elided, so comparators are recognized by name. A comparator it
+// fails to recognize, such as one wrapped in a closure, only loses the fast
+// path.
+var (
+	minPriorityName          = funcName(MinPriority[int])
+	minPriorityThenValueName = funcName(MinPriorityThenValue[int])
+	maxPriorityName          = funcName(MaxPriority[int])
+)
+
+// funcName returns the runtime's name for the code of fn.
+func funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
+
+// orderOf returns the heap order of less.
+func orderOf[T any](less func(a, b *Item[T]) bool) heapOrder {
+	if less == nil {
+		return orderMin
+	}
+	switch funcName(less) {
+	case minPriorityName, minPriorityThenValueName:
+		return orderMin
+	case maxPriorityName:
+		return orderMax
+	}
+	return orderUnknown
+}
+
+// PriorityBounds returns the lowest and highest raw priorities in the queue,
+// in O(1) while the cached extremes stay queued (see Min and Max). ok is
+// false when the queue is empty.
+func (pq *PriorityQueue[T]) PriorityBounds() (min, max int64, ok bool) {
+	lo, ok := pq.Min()
+	if !ok {
+		return 0, 0, false
+	}
+	hi, _ := pq.Max()
+	return lo.priority, hi.priority, true
+}
+
+// boundsAdded updates the cached extremes for a newly pushed item.
+func (pq *PriorityQueue[T]) boundsAdded(item *Item[T]) {
+	if len(pq.items) == 1 {
+		pq.minItem, pq.maxItem = item, item
+		return
+	}
+	if pq.minItem != nil && item.priority < pq.minItem.priority {
+		pq.minItem = item
+	}
+	if pq.maxItem != nil && item.priority > pq.maxItem.priority {
+		pq.maxItem = item
+	}
+}
+
+// boundsRemoved drops a cached extreme if item was it.
+func (pq *PriorityQueue[T]) boundsRemoved(item *Item[T]) {
+	if item == pq.minItem {
+		pq.minItem = nil
+	}
+	if item == pq.maxItem {
+		pq.maxItem = nil
+	}
+}
+
+// boundsUpdated updates the cached extremes after item's priority changed
+// from old.
+func (pq *PriorityQueue[T]) boundsUpdated(item *Item[T], old int64) {
+	switch {
+	case item == pq.minItem:
+		if item.priority > old {
+			pq.minItem = nil
+		}
+	case pq.minItem != nil && item.priority < pq.minItem.priority:
+		pq.minItem = item
+	}
+	switch {
+	case item == pq.maxItem:
+		if item.priority < old {
+			pq.maxItem = nil
+		}
+	case pq.maxItem != nil && item.priority > pq.maxItem.priority:
+		pq.maxItem = item
+	}
+}
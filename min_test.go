@@ -0,0 +1,119 @@
+package priorty_queue
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// referenceBounds finds the lowest and highest priorities by scanning.
+func referenceBounds[T any](pq *PriorityQueue[T]) (lo, hi int64) {
+	lo, hi = pq.items[0].priority, pq.items[0].priority
+	for _, item := range pq.items {
+		lo, hi = min(lo, item.priority), max(hi, item.priority)
+	}
+	return lo, hi
+}
+
+func TestMinRandomized(t *testing.T) {
+	for _, less := range []func(a, b *Item[int]) bool{nil, MaxPriority[int]} {
+		r := rand.New(rand.NewPCG(3, 4))
+		pq := NewPriorityQueue(WithComparator(less))
+		var items []*Item[int]
+		for step := range 2000 {
+			switch op := r.IntN(4); {
+			case op < 2 || pq.Len() == 0:
+				item := NewItem(step, r.Int64N(1000))
+				pq.PushItem(item)
+				items = append(items, item)
+			case op < 3:
+				pq.PopItem()
+			default:
+				pq.Update(items[r.IntN(len(items))], r.Int64N(1000))
+			}
+			lo, ok := pq.Min()
+			if pq.Len() == 0 {
+				if ok {
+					t.Fatalf("step %d: Min() of an empty queue = ok", step)
+				}
+				continue
+			}
+			if want, _ := referenceBounds(pq); !ok || lo.priority != want || !pq.owns(lo) {
+				t.Fatalf("step %d: Min() = %d, want queued item with %d", step, lo.priority, want)
+			}
+		}
+	}
+}
+
+func TestPriorityBoundsRandomized(t *testing.T) {
+	comparators := map[string]func(a, b *Item[int]) bool{
+		"min":         nil,
+		"max":         MaxPriority[int],
+		"then-value":  MinPriorityThenValue[int],
+		"effective":   MinEffectivePriority[int],
+		"then-larger": ByPriorityThen(func(a, b *Item[int]) bool { return a.value > b.value }),
+	}
+	for name, less := range comparators {
+		r := rand.New(rand.NewPCG(1, 2))
+		pq := NewPriorityQueue(WithComparator(less))
+		var items []*Item[int]
+		for step := range 2000 {
+			switch op := r.IntN(10); {
+			case op < 5 || pq.Len() == 0:
+				item := NewItem(step, r.Int64N(1000))
+				pq.PushItem(item)
+				items = append(items, item)
+			case op < 7:
+				pq.PopItem()
+			case op < 9:
+				pq.Update(items[r.IntN(len(items))], r.Int64N(1000))
+			default:
+				pq.Remove(items[r.IntN(len(items))])
+			}
+			if pq.Len() == 0 {
+				if _, _, ok := pq.PriorityBounds(); ok {
+					t.Fatalf("%s: step %d: bounds reported for an empty queue", name, step)
+				}
+				continue
+			}
+			lo, hi, _ := pq.PriorityBounds()
+			if wantLo, wantHi := referenceBounds(pq); lo != wantLo || hi != wantHi {
+				t.Fatalf("%s: step %d: bounds (%d, %d), want (%d, %d)", name, step, lo, hi, wantLo, wantHi)
+			}
+		}
+	}
+}
+
+func TestMinMaxUseTopForKnownComparators(t *testing.T) {
+	tests := []struct {
+		name string
+		less func(a, b *Item[string]) bool
+		want heapOrder
+	}{
+		{"default", nil, orderMin},
+		{"MinPriority", MinPriority[string], orderMin},
+		{"MinPriorityThenValue", MinPriorityThenValue[string], orderMin},
+		{"MaxPriority", MaxPriority[string], orderMax},
+		{"MinEffectivePriority", MinEffectivePriority[string], orderUnknown},
+		{"closure", func(a, b *Item[string]) bool { return a.priority < b.priority }, orderUnknown},
+	}
+	for _, tt := range tests {
+		pq := NewPriorityQueue(WithComparator(tt.less))
+		if got := pq.topOrder(); got != tt.want {
+			t.Errorf("%s: order %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	pq := NewPriorityQueue[string]()
+	for i := range 100 {
+		pq.PushValue("v", int64(i))
+	}
+	for pq.Len() > 1 {
+		pq.PopItem()
+		if lo, _ := pq.Min(); lo != pq.items[0] {
+			t.Fatal("Min under MinPriority is not the top")
+		}
+		if pq.minItem != nil {
+			t.Fatal("Min under MinPriority rescanned")
+		}
+	}
+}
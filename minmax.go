@@ -0,0 +1,161 @@
+package priorty_queue
+
+import "math/bits"
+
+// MinMaxPriorityQueue is a double-ended priority queue backed by a min-max
+// heap: nodes on even levels (the root is level 0) are no greater than all of
+// their descendants and nodes on odd levels are no smaller. That puts the
+// lowest priority at the root and the highest at one of its children, so both
+// ends can be peeked in O(1) and pushed or popped in O(log n).
+//
+// It holds the same Items as PriorityQueue and keeps their index up to date.
+// The zero value is an empty queue ready to use.
+type MinMaxPriorityQueue[T any] struct {
+	items []*Item[T]
+}
+
+// Len returns the number of items in the queue.
+func (q *MinMaxPriorityQueue[T]) Len() int { return len(q.items) }
+
+// PushItem adds item to the queue.
+func (q *MinMaxPriorityQueue[T]) PushItem(item *Item[T]) {
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+	q.bubbleUp(item.index)
+}
+
+// PeekMin returns the lowest-priority item without removing it.
+func (q *MinMaxPriorityQueue[T]) PeekMin() (*Item[T], bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.items[0], true
+}
+
+// PeekMax returns the highest-priority item without removing it.
+func (q *MinMaxPriorityQueue[T]) PeekMax() (*Item[T], bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.items[q.maxIndex()], true
+}
+
+// PopMin removes and returns the lowest-priority item.
+func (q *MinMaxPriorityQueue[T]) PopMin() (*Item[T], bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.removeAt(0), true
+}
+
+// PopMax removes and returns the highest-priority item.
+func (q *MinMaxPriorityQueue[T]) PopMax() (*Item[T], bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.removeAt(q.maxIndex()), true
+}
+
+// maxIndex returns the slot of the highest-priority item in a non-empty heap.
+func (q *MinMaxPriorityQueue[T]) maxIndex() int {
+	switch len(q.items) {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	}
+	if q.better(2, 1, false) {
+		return 2
+	}
+	return 1
+}
+
+// removeAt takes the item in slot i out of the heap, filling the hole with the
+// last item and trickling it down.
+func (q *MinMaxPriorityQueue[T]) removeAt(i int) *Item[T] {
+	item := q.items[i]
+	last := len(q.items) - 1
+	q.swap(i, last)
+	q.items[last] = nil
+	q.items = q.items[:last]
+	item.index = -1 // for safety
+	if i < last {
+		q.trickleDown(i)
+	}
+	return item
+}
+
+// isMinLevel reports whether slot i lies on a min (even) level.
+func isMinLevel(i int) bool {
+	return (bits.Len(uint(i+1))-1)%2 == 0
+}
+
+// better reports whether slot i should sit above slot j: lower priority on
+// min levels, higher priority on max levels.
+func (q *MinMaxPriorityQueue[T]) better(i, j int, min bool) bool {
+	if min {
+		return q.items[i].priority < q.items[j].priority
+	}
+	return q.items[i].priority > q.items[j].priority
+}
+
+func (q *MinMaxPriorityQueue[T]) swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+func (q *MinMaxPriorityQueue[T]) bubbleUp(i int) {
+	if i == 0 {
+		return
+	}
+	min := isMinLevel(i)
+	parent := (i - 1) / 2
+	if q.better(parent, i, min) {
+		// The new item belongs on the parent's levels instead.
+		q.swap(i, parent)
+		q.bubbleUpGrand(parent, !min)
+		return
+	}
+	q.bubbleUpGrand(i, min)
+}
+
+// bubbleUpGrand moves slot i up through its grandparents, which share its
+// min or max level type.
+func (q *MinMaxPriorityQueue[T]) bubbleUpGrand(i int, min bool) {
+	for i >= 3 {
+		grand := ((i-1)/2 - 1) / 2
+		if !q.better(i, grand, min) {
+			return
+		}
+		q.swap(i, grand)
+		i = grand
+	}
+}
+
+func (q *MinMaxPriorityQueue[T]) trickleDown(i int) {
+	min := isMinLevel(i)
+	n := len(q.items)
+	for {
+		// Find the best of i's children and grandchildren.
+		m := -1
+		first := 2*i + 1
+		for _, c := range [6]int{first, first + 1, 2*first + 1, 2*first + 2, 2*first + 3, 2*first + 4} {
+			if c < n && (m < 0 || q.better(c, m, min)) {
+				m = c
+			}
+		}
+		if m < 0 || !q.better(m, i, min) {
+			return
+		}
+		q.swap(m, i)
+		if m <= first+1 {
+			// m is a child, which has nothing below it out of order.
+			return
+		}
+		if parent := (m - 1) / 2; q.better(parent, m, min) {
+			q.swap(m, parent)
+		}
+		i = m
+	}
+}
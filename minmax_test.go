@@ -0,0 +1,45 @@
+package priorty_queue
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestMinMaxAgainstSorted(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var q MinMaxPriorityQueue[int]
+	var ref []int64
+	for i := range 1000 {
+		p := rng.Int64N(500)
+		q.PushItem(NewItem(i, p))
+		ref = append(ref, p)
+	}
+	slices.Sort(ref)
+	for len(ref) > 0 {
+		lo, _ := q.PeekMin()
+		hi, _ := q.PeekMax()
+		if lo.priority != ref[0] || hi.priority != ref[len(ref)-1] {
+			t.Fatalf("min/max = %d/%d, want %d/%d", lo.priority, hi.priority, ref[0], ref[len(ref)-1])
+		}
+		var item *Item[int]
+		if rng.IntN(2) == 0 {
+			item, _ = q.PopMin()
+			ref = ref[1:]
+		} else {
+			item, _ = q.PopMax()
+			ref = ref[:len(ref)-1]
+		}
+		if item.index != -1 {
+			t.Fatalf("popped item index = %d, want -1", item.index)
+		}
+		for i, it := range q.items {
+			if it.index != i {
+				t.Fatalf("slot %d holds index %d", i, it.index)
+			}
+		}
+	}
+	if _, ok := q.PopMax(); ok {
+		t.Error("PopMax() on empty = ok")
+	}
+}
@@ -0,0 +1,18 @@
+package priorty_queue
+
+import "fmt"
+
+// logOp records op on item in the WithOpLog writer, if there is one. The
+// check is kept out of writeOp so that it inlines into the hot paths.
+func (pq *PriorityQueue[T]) logOp(op string, item *Item[T]) {
+	if pq.opLog != nil {
+		pq.writeOp(op, item)
+	}
+}
+
+func (pq *PriorityQueue[T]) writeOp(op string, item *Item[T]) {
+	_, err := fmt.Fprintf(pq.opLog, "%d %s %d %v\n", pq.NowMillis(), op, item.priority, item.value)
+	if err != nil && pq.opLogErr != nil {
+		pq.opLogErr(err)
+	}
+}
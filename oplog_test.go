@@ -0,0 +1,75 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOpLogRecordsOperationsInOrder(t *testing.T) {
+	var log bytes.Buffer
+	pq := NewPriorityQueue(WithOpLog[string](&log), WithClock[string](&fakeClock{now: 7}))
+	a := pq.PushValue("a", 10).Item()
+	pq.PushValue("b", 20)
+	pq.Update(a, 30)
+	pq.PopItem()
+	pq.Remove(a)
+	want := strings.Join([]string{
+		"7 push 10 a",
+		"7 push 20 b",
+		"7 update 30 a",
+		"7 pop 20 b",
+		"7 remove 30 a",
+	}, "\n") + "\n"
+	if got := log.String(); got != want {
+		t.Errorf("op log:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOpLogCoversBulkOperations(t *testing.T) {
+	var log, otherLog bytes.Buffer
+	clock := WithClock[string](&fakeClock{})
+	pq := NewPriorityQueue(WithOpLog[string](&log), clock)
+	a := NewItem("a", 1)
+	pq.PushItem(a)
+	pq.UpdateBatch(map[*Item[string]]int64{a: 2})
+	pq.MapPriorities(func(p int64) int64 { return p + 1 })
+	a.SetPriority(4)
+	pq.FixByValue("a")
+
+	other := NewPriorityQueue(WithOpLog[string](&otherLog), clock)
+	other.PushValue("b", 5)
+	pq.Merge(other)
+	due, _ := pq.Split(4)
+
+	want := "0 push 1 a\n0 update 2 a\n0 update 3 a\n0 update 4 a\n0 push 5 b\n0 remove 4 a\n0 remove 5 b\n"
+	if got := log.String(); got != want {
+		t.Errorf("op log:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := otherLog.String(), "0 push 5 b\n0 remove 5 b\n"; got != want {
+		t.Errorf("merged-from op log:\n%s\nwant:\n%s", got, want)
+	}
+	if due.Len() != 1 {
+		t.Errorf("Split(4) due %d items, want 1", due.Len())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestOpLogErrorsAreReported(t *testing.T) {
+	var errs []error
+	pq := NewPriorityQueue(WithOpLog[string](failingWriter{}), WithOpLogErrors[string](func(err error) {
+		errs = append(errs, err)
+	}))
+	pq.PushValue("a", 1)
+	pq.PopItem()
+	if len(errs) != 2 {
+		t.Errorf("reported %d errors, want 2", len(errs))
+	}
+	if pq.Len() != 0 {
+		t.Errorf("Len = %d, want 0", pq.Len())
+	}
+}
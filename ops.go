@@ -0,0 +1,64 @@
+package priorty_queue
+
+import "fmt"
+
+// OpKind selects the action an Op performs.
+type OpKind uint8
+
+const (
+	// OpPush pushes a new item holding Op.Value at Op.Priority.
+	OpPush OpKind = iota
+	// OpPop pops the top item, if any.
+	OpPop
+	// OpUpdate sets the priority of the item at heap slot Op.Slot to
+	// Op.Priority.
+	OpUpdate
+	// OpRemove removes the item at heap slot Op.Slot.
+	OpRemove
+)
+
+// Op is one step of a scripted sequence for ApplyOps. Slot is taken modulo
+// the queue length, so any value names a valid item and randomly generated
+// sequences, such as a fuzzer's, never go out of range.
+type Op[T any] struct {
+	Kind     OpKind
+	Value    T
+	Priority int64
+	Slot     int
+}
+
+// ApplyOps applies ops to pq in order and checks the heap with Validate after
+// every step. It returns an error naming the first op after which the heap
+// was invalid, or nil. A given sequence always has the same effect, which
+// makes ApplyOps suitable as the body of a fuzz target. Update and remove
+// ops on an empty queue, and ops of unknown kind, do nothing.
+func ApplyOps[T any](pq *PriorityQueue[T], ops []Op[T]) error {
+	for i, op := range ops {
+		switch op.Kind {
+		case OpPush:
+			pq.PushItem(NewItem(op.Value, op.Priority))
+		case OpPop:
+			pq.PopItem()
+		case OpUpdate:
+			if n := pq.Len(); n > 0 {
+				pq.Update(pq.items[slot(op.Slot, n)], op.Priority)
+			}
+		case OpRemove:
+			if n := pq.Len(); n > 0 {
+				pq.Remove(pq.items[slot(op.Slot, n)])
+			}
+		}
+		if err := pq.Validate(); err != nil {
+			return fmt.Errorf("op %d (kind %d): %w", i, op.Kind, err)
+		}
+	}
+	return nil
+}
+
+// slot maps any int onto [0, n).
+func slot(i, n int) int {
+	if i %= n; i < 0 {
+		i += n
+	}
+	return i
+}
@@ -0,0 +1,97 @@
+package priorty_queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// decodeOps turns fuzzer bytes into ops, four bytes per op: kind, value,
+// priority and slot.
+func decodeOps(data []byte) []Op[int] {
+	ops := make([]Op[int], 0, len(data)/4)
+	for ; len(data) >= 4; data = data[4:] {
+		ops = append(ops, Op[int]{
+			Kind:     OpKind(data[0] % 4),
+			Value:    int(data[1]),
+			Priority: int64(int8(data[2])),
+			Slot:     int(data[3]),
+		})
+	}
+	return ops
+}
+
+// sorted returns ps sorted in place.
+func sorted(ps []int64) []int64 {
+	slices.Sort(ps)
+	return ps
+}
+
+// priorities returns the priorities of pq's items in heap order.
+func priorities[T any](pq *PriorityQueue[T]) []int64 {
+	ps := make([]int64, len(pq.items))
+	for i, item := range pq.items {
+		ps[i] = item.priority
+	}
+	return ps
+}
+
+func FuzzPriorityQueue(f *testing.F) {
+	f.Add([]byte{})
+	// Pushes followed by pops.
+	f.Add([]byte{0, 1, 5, 0, 0, 2, 3, 0, 0, 3, 9, 0, 1, 0, 0, 0, 1, 0, 0, 0})
+	// Updates moving items both ways, then removes from the middle.
+	f.Add([]byte{0, 1, 10, 0, 0, 2, 20, 0, 0, 3, 30, 0, 0, 4, 40, 0,
+		2, 0, 0xf6, 3, 2, 0, 50, 0, 3, 0, 0, 1, 3, 0, 0, 2, 1, 0, 0, 0})
+	// Ops on an empty queue and equal priorities.
+	f.Add([]byte{1, 0, 0, 0, 2, 0, 7, 9, 3, 0, 0, 4, 0, 5, 7, 0, 0, 6, 7, 0, 0, 7, 7, 1, 3, 0, 0, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ops := decodeOps(data)
+		pq := NewPriorityQueue[int]()
+		for i, op := range ops {
+			before := sorted(priorities(pq))
+			if err := ApplyOps(pq, ops[i:i+1]); err != nil {
+				t.Fatalf("op %d: %v", i, err)
+			}
+			// A pop must take exactly one lowest priority.
+			if after := sorted(priorities(pq)); op.Kind == OpPop && len(before) > 0 &&
+				!slices.Equal(after, before[1:]) {
+				t.Fatalf("op %d: pop left %v from %v", i, after, before)
+			}
+		}
+		// Replaying the whole sequence must end in the same heap.
+		replay := NewPriorityQueue[int]()
+		if err := ApplyOps(replay, ops); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := priorities(replay), priorities(pq); !slices.Equal(got, want) {
+			t.Fatalf("replay gave heap %v, want %v", got, want)
+		}
+		want := sorted(priorities(pq))
+		var got []int64
+		for pq.Len() > 0 {
+			item, _ := pq.PopItem()
+			got = append(got, item.priority)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("drained %v, want %v", got, want)
+		}
+	})
+}
+
+func TestApplyOpsReportsCorruption(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	if err := ApplyOps(pq, []Op[int]{
+		{Kind: OpPush, Value: 1, Priority: 1},
+		{Kind: OpPush, Value: 2, Priority: 2},
+		{Kind: OpPush, Value: 3, Priority: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Break the heap behind the queue's back; the next op must notice.
+	pq.items[0].priority = 9
+	err := ApplyOps(pq, []Op[int]{{Kind: OpRemove, Slot: 5}, {Kind: OpPop}})
+	if err == nil || !strings.Contains(err.Error(), "op 0") {
+		t.Fatalf("ApplyOps() = %v, want an error for op 0", err)
+	}
+}
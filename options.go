@@ -0,0 +1,171 @@
+package priorty_queue
+
+import "io"
+
+// Option configures a PriorityQueue built by NewPriorityQueue. Without any
+// options a queue is an unbounded min-heap (MinPriority) with no value index
+// and no OnRemove hook.
+type Option[T any] func(*config[T])
+
+// config collects the settings applied by Options before a queue is built.
+type config[T any] struct {
+	capacity   int
+	less       func(a, b *Item[T]) bool
+	maxSize    int
+	index      valueIndex[T]
+	onRemove   func(*Item[T])
+	tombstones int
+	clock      Clock
+	name       string
+	opLog      io.Writer
+	opLogErr   func(error)
+
+	// hardCapacity, if set, fixes both capacity and maxSize; see
+	// WithHardCapacity.
+	hardCapacity int
+
+	jitterMax   int64
+	jitterSeed  uint64
+	shrinkBelow float64
+
+	priorityRange            bool
+	minPriority, maxPriority int64
+}
+
+// WithCapacity pre-allocates room for n items in the backing slice. A
+// negative n is treated as 0.
+func WithCapacity[T any](n int) Option[T] {
+	return func(c *config[T]) { c.capacity = max(n, 0) }
+}
+
+// WithComparator orders the queue by less, which reports whether a should be
+// popped before b. The default is MinPriority.
+func WithComparator[T any](less func(a, b *Item[T]) bool) Option[T] {
+	return func(c *config[T]) { c.less = less }
+}
+
+// WithMaxSize bounds the queue at n items: PushItem rejects items once it is
+// full. 0, the default, means unbounded. Pushing directly with heap.Push
+// bypasses the bound.
+func WithMaxSize[T any](n int) Option[T] {
+	return func(c *config[T]) { c.maxSize = n }
+}
+
+// WithValueIndex maintains a value-to-item index so Contains, GetByValue and
+// UpdateByValue run in O(1) instead of scanning the queue. It costs a map
+// entry per item and some bookkeeping on every push and pop. Off by default.
+// It needs a comparable T; see WithValueIndexBy for other payloads.
+func WithValueIndex[T comparable](enabled bool) Option[T] {
+	return func(c *config[T]) {
+		c.index = nil
+		if enabled {
+			c.index = newKeyedIndex(identity[T], 0)
+		}
+	}
+}
+
+// WithValueIndexBy maintains a value index like WithValueIndex, keyed by
+// key(value) instead of the value itself, so payloads holding slices or maps
+// can be indexed by an ID field. The by-value methods then match values by
+// their key: two values with the same key are the same value to Contains,
+// UpdateByValue, RemoveByValues, Equal and the rest.
+func WithValueIndexBy[T any, K comparable](key func(T) K) Option[T] {
+	return func(c *config[T]) { c.index = newKeyedIndex(key, 0) }
+}
+
+// WithOnRemove registers fn to be called exactly once for every item that
+// leaves the queue through Pop, Remove, PopExpired or another removing method.
+// Reprioritizing an item does not call it. fn runs after the heap has been
+// fixed, so it may call back into the queue.
+func WithOnRemove[T any](fn func(*Item[T])) Option[T] {
+	return func(c *config[T]) { c.onRemove = fn }
+}
+
+// WithTombstones keeps the last n items removed with RemoveWithTombstone so
+// they can be brought back with Restore. 0, the default, keeps none.
+func WithTombstones[T any](n int) Option[T] {
+	return func(c *config[T]) { c.tombstones = n }
+}
+
+// WithPriorityRange clamps priorities into [min, max] wherever the queue sets
+// one, guarding against garbage timestamps: on every way in (PushItem and the
+// helpers built on it, FastPush, PushAll, ReplaceTop, Merge, FromSlices and
+// the decoders) and on every reprioritization (Update, Adjust, UpdateBatch
+// and MapPriorities). Only SetPriority, which bypasses the queue, is not
+// clamped. Stats reports how many priorities were clamped.
+func WithPriorityRange[T any](min, max int64) Option[T] {
+	return func(c *config[T]) {
+		c.priorityRange, c.minPriority, c.maxPriority = true, min, max
+	}
+}
+
+// WithClock makes the queue read the current time from clock instead of the
+// system clock, so tests can drive expiry with a fake clock.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(c *config[T]) { c.clock = clock }
+}
+
+// WithHardCapacity allocates the backing slice for exactly n items up front
+// and makes PushItem, PushAll and FastPush reject items once n are queued,
+// so the slice never reallocates and the queue's footprint stays fixed.
+// Unlike BoundedPriorityQueue nothing is evicted: the newcomer is refused.
+// It overrides WithCapacity and any larger WithMaxSize. FromSlices,
+// UnmarshalJSON and GobDecode refuse more than n items and Merge moves at
+// most as many as fit; only calling heap.Push directly bypasses the limit
+// and can grow the slice.
+func WithHardCapacity[T any](n int) Option[T] {
+	return func(c *config[T]) { c.hardCapacity = max(n, 0) }
+}
+
+// WithName labels the queue, for telling queues apart in Stats, String and
+// RegisteredQueues.
+func WithName[T any](name string) Option[T] {
+	return func(c *config[T]) { c.name = name }
+}
+
+// WithJitter adds a pseudo-random offset in [0, maxMillis] to the priority of
+// every new item pushed with PushItem, FastPush, PushAll or ReplaceTop, so
+// items created in the same millisecond spread out instead of expiring
+// together. Items that have been queued before, such as those brought back
+// with Requeue or Restore, are not jittered again. This deliberately
+// perturbs the order of items pushed within maxMillis of each other. The
+// offsets come from a generator seeded with seed, so a given seed and push
+// sequence always produce the same priorities. A maxMillis of 0 or less
+// disables it.
+func WithJitter[T any](maxMillis int64, seed uint64) Option[T] {
+	return func(c *config[T]) { c.jitterMax, c.jitterSeed = maxMillis, seed }
+}
+
+// WithShrinkOnPop makes every pop reallocate the backing slice to twice Len
+// once Len falls below fraction of its capacity, so a queue that drains from
+// millions of items to a handful releases the memory promptly. Each shrink
+// copies the remaining items, but the next one only comes after Len has
+// fallen by a further constant factor, so the copying is amortized O(1) per
+// pop. Slices of 64 or fewer slots are never shrunk.
+// fraction must lie in (0, 0.5); anything else leaves shrinking off.
+func WithShrinkOnPop[T any](fraction float64) Option[T] {
+	return func(c *config[T]) { c.shrinkBelow = fraction }
+}
+
+// WithOpLog writes an audit line to w for every item pushed, popped, updated
+// or removed, in the order the changes happen:
+//
+//	<millis> <op> <priority> <value>
+//
+// where millis is the queue's clock (see WithClock), op is push, pop, update
+// or remove, and value is formatted with %v. Bulk operations log one line per
+// item they change: Clear, Split and the bulk removals a remove, PushAll and
+// Merge a push (Merge also logs a remove in the queue merged from), and
+// UpdateBatch, MapPriorities and FixByValue an update. The only mutations not
+// logged are the decoders (UnmarshalJSON and GobDecode), which replace the
+// contents wholesale. A write error never disturbs the queue; report it with
+// WithOpLogErrors. Without this option nothing is formatted or written.
+func WithOpLog[T any](w io.Writer) Option[T] {
+	return func(c *config[T]) { c.opLog = w }
+}
+
+// WithOpLogErrors calls fn with every error returned by the WithOpLog
+// writer. Without it, write errors are dropped.
+func WithOpLogErrors[T any](fn func(error)) Option[T] {
+	return func(c *config[T]) { c.opLogErr = fn }
+}
@@ -0,0 +1,273 @@
+package priorty_queue
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOptionCombinations(t *testing.T) {
+	// Defaults: an unbounded min-heap without a value index.
+	pq := NewPriorityQueue[string]()
+	for i := range 100 {
+		if !pq.PushItem(NewItem("x", int64(100-i))) {
+			t.Fatal("default queue rejected a push")
+		}
+	}
+	if pq.byValue != nil {
+		t.Error("default queue has a value index")
+	}
+	if top, _ := pq.Peek(); top.priority != 1 {
+		t.Errorf("default top priority %d, want 1", top.priority)
+	}
+
+	pq = NewPriorityQueue(
+		WithCapacity[string](8),
+		WithMaxSize[string](3),
+		WithComparator(MaxPriority[string]),
+		WithValueIndex[string](true),
+	)
+	if pq.Cap() != 8 {
+		t.Errorf("Cap() = %d, want 8", pq.Cap())
+	}
+	for i, v := range []string{"a", "b", "c", "d"} {
+		if ok := pq.PushItem(NewItem(v, int64(i))); ok != (i < 3) {
+			t.Errorf("PushItem(%s) = %v with max size 3", v, ok)
+		}
+	}
+	if !pq.Contains("b") || pq.Contains("d") {
+		t.Error("value index does not match the queue")
+	}
+	if got := popValues(pq); !slices.Equal(got, []string{"c", "b", "a"}) {
+		t.Errorf("max-heap pop order %v, want [c b a]", got)
+	}
+}
+
+func jitteredPriorities(seed uint64) []int64 {
+	pq := NewPriorityQueue(WithJitter[int](50, seed))
+	for i := range 1000 {
+		pq.PushItem(NewItem(i, 1000))
+	}
+	priorities := make([]int64, 0, pq.Len())
+	for _, item := range pq.items {
+		priorities = append(priorities, item.priority)
+	}
+	return priorities
+}
+
+func TestWithJitterSpreadsPriorities(t *testing.T) {
+	priorities := jitteredPriorities(7)
+	distinct := map[int64]bool{}
+	for _, p := range priorities {
+		if p < 1000 || p > 1050 {
+			t.Fatalf("jittered priority %d outside [1000, 1050]", p)
+		}
+		distinct[p] = true
+	}
+	if len(distinct) < 40 {
+		t.Errorf("only %d distinct priorities across a 51ms window", len(distinct))
+	}
+	if !slices.Equal(priorities, jitteredPriorities(7)) {
+		t.Error("same seed produced different priorities")
+	}
+	if slices.Equal(priorities, jitteredPriorities(8)) {
+		t.Error("different seeds produced the same priorities")
+	}
+}
+
+func TestWithJitterOnlyNewItems(t *testing.T) {
+	pq := NewPriorityQueue(WithJitter[string](10, 1), WithTombstones[string](1))
+	item := NewItem("a", 1060)
+	pq.PushItem(item)
+	pushed := item.Priority()
+
+	pq.RemoveWithTombstone(item)
+	pq.Restore("a")
+	if item.Priority() != pushed {
+		t.Errorf("Restore moved the priority from %d to %d", pushed, item.Priority())
+	}
+	pq.PopItem()
+	if err := pq.Requeue(item, 2000); err != nil {
+		t.Fatal(err)
+	}
+	if item.Priority() != 2000 {
+		t.Errorf("Requeue jittered the priority to %d, want 2000", item.Priority())
+	}
+}
+
+func TestWithPriorityRangeClampsPushAndUpdate(t *testing.T) {
+	pq := NewPriorityQueue(WithPriorityRange[string](0, 10))
+	item := pq.PushValue("high", 1000).Item()
+	if item.Priority() != 10 {
+		t.Errorf("pushed priority stored as %d, want 10", item.Priority())
+	}
+	if got := pq.Stats().Clamped; got != 1 {
+		t.Errorf("Clamped = %d, want 1", got)
+	}
+	pq.Update(item, -5)
+	if item.Priority() != 0 {
+		t.Errorf("updated priority stored as %d, want 0", item.Priority())
+	}
+	pq.Update(item, 5)
+	if got := pq.Stats().Clamped; got != 2 {
+		t.Errorf("Clamped = %d after an in-range update, want 2", got)
+	}
+}
+
+func TestWithPriorityRangeClampsEveryPath(t *testing.T) {
+	inRange := func(t *testing.T, pq *PriorityQueue[string]) {
+		t.Helper()
+		mustValidate(t, pq)
+		for _, item := range pq.items {
+			if item.priority < 0 || item.priority > 10 {
+				t.Errorf("%s has priority %d outside [0, 10]", item.value, item.priority)
+			}
+		}
+	}
+	build := func() *PriorityQueue[string] {
+		pq := NewPriorityQueue(WithPriorityRange[string](0, 10))
+		pq.PushValue("a", 5)
+		return pq
+	}
+	tests := map[string]func(pq *PriorityQueue[string]){
+		"PushAll": func(pq *PriorityQueue[string]) {
+			pq.PushAll([]*Item[string]{NewItem("b", 1000), NewItem("c", -1000)})
+		},
+		"PushAll small batch": func(pq *PriorityQueue[string]) {
+			for i := range 10 {
+				pq.PushValue("filler", int64(i))
+			}
+			pq.PushAll([]*Item[string]{NewItem("b", 1000)})
+		},
+		"FastPush":   func(pq *PriorityQueue[string]) { pq.FastPush(NewItem("b", 1000)) },
+		"ReplaceTop": func(pq *PriorityQueue[string]) { pq.ReplaceTop(NewItem("b", 1000)) },
+		"Merge": func(pq *PriorityQueue[string]) {
+			other := NewPriorityQueue[string]()
+			other.PushValue("b", 1000)
+			pq.Merge(other)
+		},
+		"UpdateBatch": func(pq *PriorityQueue[string]) {
+			item, _ := pq.Peek()
+			pq.UpdateBatch(map[*Item[string]]int64{item: 1000})
+		},
+		"MapPriorities": func(pq *PriorityQueue[string]) {
+			pq.MapPriorities(func(p int64) int64 { return p * 1000 })
+		},
+	}
+	for name, op := range tests {
+		t.Run(name, func(t *testing.T) {
+			pq := build()
+			op(pq)
+			inRange(t, pq)
+			if pq.Stats().Clamped == 0 {
+				t.Error("Clamped = 0")
+			}
+		})
+	}
+
+	pq, err := FromSlices([]string{"a", "b"}, []int64{-1, 11}, WithPriorityRange[string](0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inRange(t, pq)
+}
+
+func TestWithHardCapacity(t *testing.T) {
+	pq := NewPriorityQueue(WithHardCapacity[int](8), WithCapacity[int](2), WithMaxSize[int](100))
+	capacity := pq.Cap()
+	if capacity != 8 {
+		t.Fatalf("Cap() = %d, want 8", capacity)
+	}
+	for i := range 8 {
+		if !pq.PushItem(NewItem(i, int64(i))) {
+			t.Fatalf("push %d rejected below the ceiling", i)
+		}
+	}
+	if pq.PushItem(NewItem(99, -1)) {
+		t.Fatal("push past the ceiling accepted")
+	}
+	if pq.Len() != 8 {
+		t.Fatalf("Len() = %d after a rejected push, want 8", pq.Len())
+	}
+	if top, _ := pq.Peek(); top.value != 0 {
+		t.Errorf("rejected push changed the top to %d", top.value)
+	}
+	pq.PopItem()
+	if !pq.PushItem(NewItem(99, -1)) {
+		t.Fatal("push after a pop rejected")
+	}
+	if pq.Cap() != capacity {
+		t.Errorf("Cap() = %d, want the fixed %d", pq.Cap(), capacity)
+	}
+	mustValidate(t, pq)
+}
+
+func TestHardCapacityBoundsLoads(t *testing.T) {
+	if _, err := FromSlices([]int{1, 2, 3}, []int64{1, 2, 3}, WithHardCapacity[int](2)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("FromSlices past the ceiling: err = %v, want ErrQueueFull", err)
+	}
+	pq, err := FromSlices([]int{3, 1}, []int64{3, 1}, WithHardCapacity[int](2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	capacity := pq.Cap()
+	data, _ := json.Marshal([]*Item[int]{NewItem(5, 5), NewItem(4, 4)})
+	if err := json.Unmarshal(data, pq); err != nil {
+		t.Fatal(err)
+	}
+	if pq.Cap() != capacity {
+		t.Errorf("Cap() = %d after UnmarshalJSON, want the fixed %d", pq.Cap(), capacity)
+	}
+	data, _ = json.Marshal([]*Item[int]{NewItem(7, 7), NewItem(8, 8), NewItem(9, 9)})
+	if err := json.Unmarshal(data, pq); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("UnmarshalJSON past the ceiling: err = %v, want ErrQueueFull", err)
+	}
+	if got := popValues(pq); !slices.Equal(got, []int{4, 5}) {
+		t.Errorf("queue after refused UnmarshalJSON pops %v, want [4 5]", got)
+	}
+
+	gobbed, _ := NewPriorityQueueFromItems(NewItem(1, 1), NewItem(2, 2), NewItem(3, 3)).GobEncode()
+	if err := pq.GobDecode(gobbed); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("GobDecode past the ceiling: err = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestWithShrinkOnPop(t *testing.T) {
+	const n = 100_000
+	pq := NewPriorityQueue(WithShrinkOnPop[int](0.25))
+	plain := NewPriorityQueue[int]()
+	for i := range n {
+		pq.PushValue(i, int64(i*7919%n))
+		plain.PushValue(i, int64(i))
+	}
+	shrinks := 0
+	for pq.Len() > 10 {
+		before := pq.Cap()
+		pq.PopItem()
+		plain.PopItem()
+		after := pq.Cap()
+		if after == before {
+			if pq.Len() < before/4 && before > 64 {
+				t.Fatalf("Len %d below a quarter of Cap %d without shrinking", pq.Len(), before)
+			}
+			continue
+		}
+		shrinks++
+		if float64(pq.Len()) >= 0.25*float64(before) || after != max(2*pq.Len(), 64) {
+			t.Fatalf("shrank from %d to %d at Len %d", before, after, pq.Len())
+		}
+		mustValidate(t, pq)
+	}
+	if shrinks == 0 || pq.Cap() > 64 {
+		t.Errorf("%d shrinks, final Cap %d, want several down to 64", shrinks, pq.Cap())
+	}
+	if plain.Cap() < n {
+		t.Errorf("Cap() without the option = %d, want it kept at %d or more", plain.Cap(), n)
+	}
+	for _, bad := range []float64{0, 0.5, -1, 2} {
+		if NewPriorityQueue(WithShrinkOnPop[int](bad)).shrinkBelow != 0 {
+			t.Errorf("WithShrinkOnPop(%v) enabled shrinking", bad)
+		}
+	}
+}
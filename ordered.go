@@ -0,0 +1,127 @@
+package priorty_queue
+
+import (
+	"cmp"
+	"container/heap"
+)
+
+// OrderedItem is an Item whose priority can be any ordered type, such as a
+// uint64 timestamp, rather than PriorityQueue's int64 milliseconds.
+type OrderedItem[T any, P cmp.Ordered] struct {
+	value    T
+	priority P
+	index    int
+	seq      uint64
+}
+
+// NewOrderedItem returns an OrderedItem holding value with the given priority.
+func NewOrderedItem[T any, P cmp.Ordered](value T, priority P) *OrderedItem[T, P] {
+	return &OrderedItem[T, P]{value: value, priority: priority}
+}
+
+// Value returns the item's value.
+func (it *OrderedItem[T, P]) Value() T { return it.value }
+
+// Priority returns the item's priority.
+func (it *OrderedItem[T, P]) Priority() P { return it.priority }
+
+// Index returns the item's position in the heap, or -1 once it has been popped.
+func (it *OrderedItem[T, P]) Index() int { return it.index }
+
+// OrderedPriorityQueue is a min-heap over OrderedItems. Equal priorities pop
+// in insertion order. Every accessor reports emptiness with an ok bool, so
+// the zero priority is an ordinary value rather than a sentinel. The zero
+// value is an empty queue ready to use.
+type OrderedPriorityQueue[T any, P cmp.Ordered] struct {
+	h orderedHeap[T, P]
+}
+
+// Len returns the number of items in the queue.
+func (q *OrderedPriorityQueue[T, P]) Len() int { return q.h.Len() }
+
+// Push adds item to the queue.
+func (q *OrderedPriorityQueue[T, P]) Push(item *OrderedItem[T, P]) { heap.Push(&q.h, item) }
+
+// Pop removes and returns the lowest-priority item. ok is false when the
+// queue is empty.
+func (q *OrderedPriorityQueue[T, P]) Pop() (*OrderedItem[T, P], bool) {
+	if q.h.Len() == 0 {
+		return nil, false
+	}
+	return heap.Pop(&q.h).(*OrderedItem[T, P]), true
+}
+
+// Peek returns the lowest-priority item without removing it. ok is false
+// when the queue is empty.
+func (q *OrderedPriorityQueue[T, P]) Peek() (*OrderedItem[T, P], bool) {
+	if q.h.Len() == 0 {
+		return nil, false
+	}
+	return q.h.items[0], true
+}
+
+// Update changes the priority of a queued item and restores the heap order.
+// It returns false if item is not in the queue.
+func (q *OrderedPriorityQueue[T, P]) Update(item *OrderedItem[T, P], priority P) bool {
+	if !q.h.owns(item) {
+		return false
+	}
+	item.priority = priority
+	heap.Fix(&q.h, item.index)
+	return true
+}
+
+// Remove deletes item from the queue. It returns false if item is not in the
+// queue.
+func (q *OrderedPriorityQueue[T, P]) Remove(item *OrderedItem[T, P]) bool {
+	if !q.h.owns(item) {
+		return false
+	}
+	heap.Remove(&q.h, item.index)
+	return true
+}
+
+// orderedHeap implements heap.Interface for OrderedPriorityQueue.
+type orderedHeap[T any, P cmp.Ordered] struct {
+	items []*OrderedItem[T, P]
+	seq   uint64
+}
+
+// owns reports whether item currently sits in this heap. A nil item never
+// does.
+func (h *orderedHeap[T, P]) owns(item *OrderedItem[T, P]) bool {
+	return item != nil && item.index >= 0 && item.index < len(h.items) && h.items[item.index] == item
+}
+
+func (h *orderedHeap[T, P]) Len() int { return len(h.items) }
+
+func (h *orderedHeap[T, P]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if c := cmp.Compare(a.priority, b.priority); c != 0 {
+		return c < 0
+	}
+	return a.seq < b.seq
+}
+
+func (h *orderedHeap[T, P]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *orderedHeap[T, P]) Push(x interface{}) {
+	item := x.(*OrderedItem[T, P])
+	h.seq++
+	item.index = len(h.items)
+	item.seq = h.seq
+	h.items = append(h.items, item)
+}
+
+func (h *orderedHeap[T, P]) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	item.index = -1 // for safety
+	h.items = h.items[:n-1]
+	return item
+}
@@ -0,0 +1,42 @@
+package priorty_queue
+
+import "testing"
+
+func TestOrderedZeroPriority(t *testing.T) {
+	var q OrderedPriorityQueue[string, uint64]
+	if _, ok := q.Peek(); ok {
+		t.Fatal("Peek() on empty = ok")
+	}
+	q.Push(NewOrderedItem("later", uint64(7)))
+	zero := NewOrderedItem("zero", uint64(0))
+	q.Push(zero)
+	top, ok := q.Peek()
+	if !ok || top != zero || top.Priority() != 0 {
+		t.Fatalf("Peek() = %v, %v; want the zero-priority item", top, ok)
+	}
+	if !q.Update(zero, 9) {
+		t.Fatal("Update() = false")
+	}
+	for _, want := range []string{"later", "zero"} {
+		if item, ok := q.Pop(); !ok || item.Value() != want {
+			t.Fatalf("Pop() = %v, %v; want %s", item, ok, want)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop() on empty = ok")
+	}
+}
+
+func TestOrderedNilItem(t *testing.T) {
+	var q OrderedPriorityQueue[string, uint64]
+	q.Push(NewOrderedItem("a", uint64(1)))
+	if q.Update(nil, 5) {
+		t.Error("Update(nil) = true")
+	}
+	if q.Remove(nil) {
+		t.Error("Remove(nil) = true")
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", q.Len())
+	}
+}
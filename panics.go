@@ -0,0 +1,32 @@
+package priorty_queue
+
+import "fmt"
+
+// rethrow, deferred by the mutators that call the comparator, re-panics any
+// panic raised during the operation, for example by a faulty comparator or
+// OnRemove hook, with the queue's name, its length and the item's value
+// added, so the trace says which queue and item were involved. item is nil
+// for batch operations that act on no single item. An error panic is wrapped
+// with %w so errors.Is and errors.As still see it.
+func (pq *PriorityQueue[T]) rethrow(op string, item *Item[T]) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	name, what := pq.label(), "<nil item>"
+	if item != nil {
+		what = fmt.Sprintf("value %v", item.value)
+	}
+	if err, ok := r.(error); ok {
+		panic(fmt.Errorf("priority queue %s: %s of %s with Len %d: %w", name, op, what, len(pq.items), err))
+	}
+	panic(fmt.Errorf("priority queue %s: %s of %s with Len %d: %v", name, op, what, len(pq.items), r))
+}
+
+// label names the queue in panic messages.
+func (pq *PriorityQueue[T]) label() string {
+	if pq.name == "" {
+		return "(unnamed)"
+	}
+	return pq.name
+}
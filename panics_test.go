@@ -0,0 +1,123 @@
+package priorty_queue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errPoisoned = errors.New("poisoned priority")
+
+// poisonedLess panics when it meets a negative priority, standing in for a
+// faulty comparator.
+func poisonedLess(a, b *Item[string]) bool {
+	if a.priority < 0 || b.priority < 0 {
+		panic(errPoisoned)
+	}
+	return a.priority < b.priority
+}
+
+// catch runs fn and returns what it panicked with.
+func catch(fn func()) (r any) {
+	defer func() { r = recover() }()
+	fn()
+	return nil
+}
+
+func TestRethrowAddsContext(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(poisonedLess), WithName[string]("ingest"))
+	for _, v := range []string{"a", "b", "c"} {
+		pq.PushValue(v, 10)
+	}
+	bad, _ := pq.GetByValue("b")
+	r := catch(func() { pq.Update(bad, -1) })
+	err, ok := r.(error)
+	if !ok {
+		t.Fatalf("Update panicked with %v, want an error", r)
+	}
+	for _, want := range []string{"ingest", "Update", "value b", "Len 3"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("panic %q does not mention %q", err, want)
+		}
+	}
+	if !errors.Is(err, errPoisoned) {
+		t.Errorf("panic %v does not wrap the original error", err)
+	}
+}
+
+func TestRethrowNonErrorPanic(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(func(a, b *Item[int]) bool {
+		panic("comparator exploded")
+	}))
+	pq.PushValue(1, 1)
+	r := catch(func() { pq.PushItem(NewItem(42, 2)) })
+	err, ok := r.(error)
+	if !ok {
+		t.Fatalf("PushItem panicked with %v, want an error", r)
+	}
+	for _, want := range []string{"(unnamed)", "PushItem", "value 42", "comparator exploded"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("panic %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRethrowNilItem(t *testing.T) {
+	pq := NewPriorityQueue(WithName[string]("ingest"))
+	r := catch(func() {
+		defer pq.rethrow("PushItem", nil)
+		panic("boom")
+	})
+	err, ok := r.(error)
+	if !ok {
+		t.Fatalf("rethrow panicked with %v, want an error", r)
+	}
+	for _, want := range []string{"ingest", "PushItem", "<nil item>", "boom"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("panic %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRethrowWrapsEveryMutator(t *testing.T) {
+	for name, tc := range map[string]struct {
+		op   func(pq *PriorityQueue[string])
+		want string
+	}{
+		"PopItem":    {func(pq *PriorityQueue[string]) { pq.PopItem() }, "value a"},
+		"FastPop":    {func(pq *PriorityQueue[string]) { pq.FastPop() }, "value a"},
+		"FastPush":   {func(pq *PriorityQueue[string]) { pq.FastPush(NewItem("x", -5)) }, "value x"},
+		"ReplaceTop": {func(pq *PriorityQueue[string]) { pq.ReplaceTop(NewItem("x", -5)) }, "value x"},
+		"PushAll": {func(pq *PriorityQueue[string]) {
+			// Large enough to take the heapify path rather than PushItem.
+			batch := []*Item[string]{NewItem("x", -5)}
+			for len(batch) < PushAllCrossover*pq.Len() {
+				batch = append(batch, NewItem("y", 20))
+			}
+			pq.PushAll(batch)
+		}, "<nil item>"},
+		"UpdateBatch": {func(pq *PriorityQueue[string]) { pq.UpdateBatch(map[*Item[string]]int64{pq.items[1]: -5}) }, "<nil item>"},
+	} {
+		pq := NewPriorityQueue(WithComparator(poisonedLess), WithName[string]("ingest"))
+		for i, v := range []string{"a", "b", "c", "d"} {
+			pq.PushValue(v, int64(10+i))
+		}
+		if name == "PopItem" || name == "FastPop" {
+			// Poison an item the sift after the pop must compare.
+			pq.items[len(pq.items)-1].priority = -1
+		}
+		err, ok := catch(func() { tc.op(pq) }).(error)
+		if !ok {
+			t.Errorf("%s did not panic with an error", name)
+			continue
+		}
+		for _, want := range []string{"ingest", name, tc.want, "Len"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: panic %q does not mention %q", name, err, want)
+			}
+		}
+		if !errors.Is(err, errPoisoned) {
+			t.Errorf("%s: panic %v does not wrap the original error", name, err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package priorty_queue
+
+import "sync"
+
+// ItemPool recycles Items through a sync.Pool to cut allocations in
+// high-churn push/pop loops. The zero value is ready to use.
+//
+// Queues never release items on their own: the caller owns an item's
+// lifecycle and must only Release it once it has left every queue and no
+// other reference to it remains. Releasing an item that is still queued or
+// still in use elsewhere is a use-after-free bug.
+type ItemPool[T any] struct {
+	pool sync.Pool
+}
+
+// Acquire returns an Item holding value and priority, reusing a released one
+// when available.
+func (p *ItemPool[T]) Acquire(value T, priority int64) *Item[T] {
+	item, _ := p.pool.Get().(*Item[T])
+	if item == nil {
+		return NewItem(value, priority)
+	}
+	item.value, item.priority = value, priority
+	return item
+}
+
+// Release zeroes item and returns it to the pool.
+func (p *ItemPool[T]) Release(item *Item[T]) {
+	*item = Item[T]{}
+	p.pool.Put(item)
+}
@@ -0,0 +1,48 @@
+package priorty_queue
+
+import "testing"
+
+func TestItemPoolReuse(t *testing.T) {
+	var pool ItemPool[string]
+	pq := NewPriorityQueue[string]()
+	item := pool.Acquire("a", 5)
+	cancelled := false
+	item.extended().cancel = func() { cancelled = true }
+	pq.PushItem(item)
+	popped, _ := pq.PopItem()
+	if !cancelled {
+		t.Fatal("popping did not run the cancel func")
+	}
+	pool.Release(popped)
+	// Whether or not the pool hands the same item back, it must be clean.
+	again := pool.Acquire("b", 7)
+	if again.value != "b" || again.priority != 7 || again.ext != nil {
+		t.Errorf("Acquire() = %q/%d with ext %v", again.value, again.priority, again.ext)
+	}
+	if !pq.PushItem(again) || pq.Len() != 1 {
+		t.Error("a recycled item was not accepted")
+	}
+}
+
+// BenchmarkItemPool compares a push/pop loop allocating with NewItem against
+// one recycling items through ItemPool.
+func BenchmarkItemPool(b *testing.B) {
+	b.Run("NewItem", func(b *testing.B) {
+		pq := NewPriorityQueue[int]()
+		b.ReportAllocs()
+		for i := range b.N {
+			pq.PushItem(NewItem(i, int64(i)))
+			pq.PopItem()
+		}
+	})
+	b.Run("ItemPool", func(b *testing.B) {
+		var pool ItemPool[int]
+		pq := NewPriorityQueue[int]()
+		b.ReportAllocs()
+		for i := range b.N {
+			pq.PushItem(pool.Acquire(i, int64(i)))
+			item, _ := pq.PopItem()
+			pool.Release(item)
+		}
+	})
+}
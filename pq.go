@@ -1,22 +1,128 @@
 package priorty_queue
 
 import (
+	"cmp"
 	"container/heap"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"strings"
 )
 
+// ErrEmptyQueue is returned by operations that need an item from an empty
+// queue.
+var ErrEmptyQueue = errors.New("priority queue is empty")
+
+// ErrQueueFull is returned by Requeue when the queue is at its WithMaxSize
+// limit, and by FromSlices and the decoders when there are more items than
+// the limit allows.
+var ErrQueueFull = errors.New("priority queue is full")
+
+// ErrItemQueued is returned by Requeue for an item that is still in the
+// queue.
+var ErrItemQueued = errors.New("item is already queued")
+
 // This priority queue manages eventBuffers that expire after a certain
 // period of inactivity (no new events).
-type Item struct {
-	value string
+//
+// An Item's index belongs to the queue that holds it, so an Item must not be
+// in two queues at the same time. Whatever index an Item carries before it
+// is pushed or built into a queue is ignored and overwritten.
+type Item[T any] struct {
+	value T
 	// The priority of the item in the queue.
 	// For our purposes, this is milliseconds since epoch
 	priority int64
 	// index is needed by update and is maintained by heap.Interface
 	// The index of this item in the heap.
 	index int
+	// seq is the item's insertion order, used to pop equal priorities FIFO.
+	seq uint64
+	// ext holds the state of opt-in features, nil until one is used.
+	ext *itemExt
+}
+
+// itemExt is the part of an Item that only some items need, kept out of line
+// so that plain items stay small.
+type itemExt struct {
+	// expiry is an optional absolute expiry in milliseconds since epoch,
+	// independent of priority. 0 means the item never expires.
+	expiry int64
+	// expIndex is the item's slot in its queue's expiry heap, meaningful
+	// only while the item is queued with a non-zero expiry.
+	expIndex int
+	// weight is a grace period in milliseconds added to priority by
+	// MinEffectivePriority; graceLost is how much of it had decayed away
+	// when FixAll last ran.
+	weight, graceLost int64
+	// lastPopped is the queue's pop counter when PopFairTie last popped the
+	// item, 0 if never.
+	lastPopped uint64
+	// meta holds caller annotations set with SetMeta; it is allocated on
+	// first use and never consulted for ordering.
+	meta map[string]string
+	// cancel, if set, is called once when the item leaves a queue.
+	cancel context.CancelFunc
 }
 
+// extended returns the item's ext, allocating it on first use.
+func (it *Item[T]) extended() *itemExt {
+	if it.ext == nil {
+		it.ext = new(itemExt)
+	}
+	return it.ext
+}
+
+// NewItem returns an Item holding value with the given priority. The index is
+// left unset until the item is pushed onto a queue.
+func NewItem[T any](value T, priority int64) *Item[T] {
+	return &Item[T]{value: value, priority: priority}
+}
+
+// NewItemWithExpiry returns an Item like NewItem that also expires at
+// expiryMillis, in milliseconds since epoch. See RemoveExpired.
+func NewItemWithExpiry[T any](value T, priority, expiryMillis int64) *Item[T] {
+	return &Item[T]{value: value, priority: priority, ext: &itemExt{expiry: expiryMillis}}
+}
+
+// NewCancelableItem returns an Item like NewItem whose cancel func is called
+// exactly once when the item leaves the queue it is in, whether by Pop,
+// Remove, PopExpired or another removing method, so work tied to the item can
+// be stopped. Reprioritizing does not call it. A nil cancel is ignored.
+func NewCancelableItem[T any](value T, priority int64, cancel context.CancelFunc) *Item[T] {
+	return &Item[T]{value: value, priority: priority, ext: &itemExt{cancel: cancel}}
+}
+
+// Value returns the item's value.
+func (it *Item[T]) Value() T { return it.value }
+
+// Priority returns the item's priority.
+func (it *Item[T]) Priority() int64 { return it.priority }
+
+// SetPriority changes the item's priority without touching any queue. For a
+// queued item the heap is left out of order until it is repaired with
+// FixByValue; prefer Update unless several fields change out-of-band.
+func (it *Item[T]) SetPriority(priority int64) { it.priority = priority }
+
+// Expiry returns the item's expiry in milliseconds since epoch, or 0 if it
+// has none.
+func (it *Item[T]) Expiry() int64 {
+	if it.ext == nil {
+		return 0
+	}
+	return it.ext.expiry
+}
+
+// Index returns the item's position in the heap, or -1 once it has been popped.
+func (it *Item[T]) Index() int { return it.index }
+
 // A Priority Queue (min heap) implemented with go's heap container.
 // Adapted from go's example at: https://golang.org/pkg/container/heap/
 //
@@ -27,53 +133,1642 @@ type Item struct {
 // The priority here will be a timestamp in milliseconds since epoch (int64)
 // with lower values (older timestamps) being at the top of the heap/queue and
 // higher values (more recent timestamps) being further down.
-// So this is a Min Heap.
+// So by default this is a Min Heap; a different comparator (see MaxPriority)
+// can be supplied to NewPriorityQueue with WithComparator to change the order.
+//
+// A priorityQueue implements heap.Interface and holds Items. The value type
+// T is the payload carried by each Item and may be any type. The by-value
+// methods (Contains, UpdateByValue and the like) match values with ==, which
+// panics for a payload that is not comparable unless the queue was built
+// WithValueIndexBy a comparable key. The zero value is an empty min-heap,
+// ready to use; Init never needs to be called before first use.
 //
-// A priorityQueue implements heap.Interface and holds Items.
-type PriorityQueue []*Item
+// The exported Push and Pop methods exist only for container/heap. Push
+// appends without restoring the heap order and Pop takes the last slot, not
+// the top, so calling them directly leaves the queue out of order until Init
+// runs. Push with PushItem (or heap.Push) and pop with PopItem (or
+// heap.Pop) instead.
+type PriorityQueue[T any] struct {
+	items []*Item[T]
+	// less reports whether a should be popped before b. nil means MinPriority.
+	less func(a, b *Item[T]) bool
+	// order is orderOf(less), kept alongside it for Min and Max.
+	order heapOrder
+	// seq is the last sequence number handed out by Push.
+	seq uint64
+	// byValue indexes the queued items by value when WithValueIndex or
+	// WithValueIndexBy is on; it is nil otherwise. A value pushed more than
+	// once maps to every item holding it, in push order.
+	byValue valueIndex[T]
+	// maxSize bounds the queue for PushItem. 0 means unbounded.
+	maxSize int
+	// fixedCap marks a WithHardCapacity queue, whose items slice is
+	// allocated once and refilled in place.
+	fixedCap bool
+	// onRemove, if set, is called once for each item that leaves the queue.
+	onRemove func(*Item[T])
+	// pushes, pops and removes count operations since the queue was created;
+	// see Stats.
+	pushes, pops, removes uint64
+	// tombstones is a ring of recently removed items that Restore can bring
+	// back; tombstoneNext is the slot the next removal overwrites.
+	tombstones    []*Item[T]
+	tombstoneNext int
+	// fairPops counts PopFairTie calls, stamping itemExt.lastPopped.
+	fairPops uint64
+	// clampRange, with clampMin and clampMax, limits pushed and updated
+	// priorities; clamped counts how many were brought into range.
+	clampRange         bool
+	clampMin, clampMax int64
+	clamped            uint64
+	// expiring holds the items with an expiry, ordered by it, so that
+	// RemoveExpired need not scan.
+	expiring expiryHeap[T]
+	// minItem and maxItem cache the items with the lowest and highest raw
+	// priority, or are nil when they must be found again by Min or Max.
+	minItem, maxItem *Item[T]
+	// maxLen is the largest Len seen since creation or the last
+	// ResetHighWaterMark.
+	maxLen int
+	// head is the top item StalledFor last saw, and headSince when it
+	// first saw it there.
+	head      *Item[T]
+	headSince int64
+	// shrinkBelow is the WithShrinkOnPop fraction, 0 when off.
+	shrinkBelow float64
+	// jitter, when set, draws the WithJitter offset in [0, jitterMax].
+	jitter    *rand.Rand
+	jitterMax int64
+	// opLog, when set, receives a line per mutation; see WithOpLog.
+	// opLogErr is told about failed writes, and removing marks the
+	// container/heap Pop that finishes a Remove.
+	opLog    io.Writer
+	opLogErr func(error)
+	removing bool
+	// deferRemoved makes Pop leave the removal hooks to its caller; see
+	// DrainToChannel.
+	deferRemoved bool
+	// name labels the queue in Stats and String; see WithName.
+	name string
+	// clock supplies the current time to NowMillis; nil means RealClock.
+	clock Clock
+}
+
+// StringItem and StringPriorityQueue are the string-valued instantiations
+// used before the queue became generic.
+type (
+	StringItem          = Item[string]
+	StringPriorityQueue = PriorityQueue[string]
+)
+
+// MinPriority orders items oldest (lowest priority) first. It is the default
+// comparator.
+func MinPriority[T any](a, b *Item[T]) bool { return a.priority < b.priority }
+
+// MaxPriority orders items newest (highest priority) first.
+func MaxPriority[T any](a, b *Item[T]) bool { return a.priority > b.priority }
+
+// ByPriorityThen returns a comparator that orders items like MinPriority and
+// consults secondary only for items of equal priority, for tie-breaks such as
+// preferring the largest buffer among equal timestamps. secondary reports
+// whether a should pop before b; ties it leaves unresolved still pop in
+// insertion order.
+func ByPriorityThen[T any](secondary func(a, b *Item[T]) bool) func(a, b *Item[T]) bool {
+	return func(a, b *Item[T]) bool {
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		return secondary(a, b)
+	}
+}
+
+// MinPriorityThenValue orders items like MinPriority but breaks ties by
+// value, smallest first, instead of by insertion order, so equal priorities
+// pop in the same order however they were pushed. It is opt-in through
+// WithComparator.
+func MinPriorityThenValue[T cmp.Ordered](a, b *Item[T]) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	return a.value < b.value
+}
+
+// NewPriorityQueue returns an empty, initialized PriorityQueue configured by
+// opts. With no options it is an unbounded min-heap without a value index,
+// the same as the zero value.
+func NewPriorityQueue[T any](opts ...Option[T]) *PriorityQueue[T] {
+	var c config[T]
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.hardCapacity > 0 {
+		c.capacity = c.hardCapacity
+		if c.maxSize == 0 || c.maxSize > c.hardCapacity {
+			c.maxSize = c.hardCapacity
+		}
+	}
+	pq := &PriorityQueue[T]{
+		items:    make([]*Item[T], 0, c.capacity),
+		less:     c.less,
+		order:    orderOf(c.less),
+		maxSize:  c.maxSize,
+		fixedCap: c.hardCapacity > 0,
+		onRemove: c.onRemove,
+		clock:    c.clock,
+		name:     c.name,
+		opLog:    c.opLog,
+		opLogErr: c.opLogErr,
+	}
+	if c.priorityRange {
+		pq.clampRange, pq.clampMin, pq.clampMax = true, c.minPriority, c.maxPriority
+	}
+	if c.shrinkBelow > 0 && c.shrinkBelow < 0.5 {
+		pq.shrinkBelow = c.shrinkBelow
+	}
+	if c.jitterMax > 0 {
+		pq.jitter = rand.New(rand.NewPCG(c.jitterSeed, 0))
+		pq.jitterMax = c.jitterMax
+	}
+	if c.tombstones > 0 {
+		pq.tombstones = make([]*Item[T], c.tombstones)
+	}
+	pq.byValue = c.index
+	heap.Init(pq)
+	return pq
+}
+
+// NewPriorityQueueFromItems builds a min-heap PriorityQueue from items and
+// heapifies it in O(n) with heap.Init instead of pushing them one at a time.
+func NewPriorityQueueFromItems[T any](items ...*Item[T]) *PriorityQueue[T] {
+	pq := &PriorityQueue[T]{}
+	pq.load(items)
+	return pq
+}
 
-func (pq PriorityQueue) Len() int { return len(pq) }
+// FromSlices builds a queue configured by opts from parallel slices of values
+// and priorities, heapifying once in O(n). It returns an error if the slices
+// differ in length, or one wrapping ErrQueueFull if opts set a WithMaxSize or
+// WithHardCapacity limit that the values exceed.
+func FromSlices[T any](values []T, priorities []int64, opts ...Option[T]) (*PriorityQueue[T], error) {
+	if len(values) != len(priorities) {
+		return nil, fmt.Errorf("FromSlices: %d values but %d priorities", len(values), len(priorities))
+	}
+	items := make([]*Item[T], len(values))
+	for i, value := range values {
+		items[i] = NewItem(value, priorities[i])
+	}
+	pq := NewPriorityQueue(opts...)
+	if err := pq.loadBounded(items); err != nil {
+		return nil, fmt.Errorf("FromSlices: %w", err)
+	}
+	return pq, nil
+}
+
+// Adapt builds a min-heap PriorityQueue over arbitrary payloads, taking each
+// item's priority from priority, and heapifies it once in O(n). It eases the
+// move from a hand-rolled container/heap type: the payload structs stay as
+// they are and only the priority field needs an accessor.
+func Adapt[T any](items []T, priority func(T) int64) *PriorityQueue[T] {
+	wrapped := make([]*Item[T], len(items))
+	for i, v := range items {
+		wrapped[i] = NewItem(v, priority(v))
+	}
+	pq := &PriorityQueue[T]{}
+	pq.load(wrapped)
+	return pq
+}
+
+// Init re-establishes the heap invariant over the queue's current items in
+// O(n) and reassigns every item's index to match its slot. The package's
+// constructors already return initialized queues; Init is for restoring order
+// after the items were rearranged in bulk. NewPriorityQueueFromItems,
+// FromSlices and PushAll assign every incoming item's index as well, so stale
+// or garbage indices never survive into the heap.
+func (pq *PriorityQueue[T]) Init() {
+	for i, item := range pq.items {
+		item.index = i
+	}
+	pq.minItem, pq.maxItem = nil, nil
+	pq.maxLen = max(pq.maxLen, len(pq.items))
+	heap.Init(pq)
+}
+
+// load replaces the queue's contents with items, numbering them in order, and
+// heapifies them in O(n).
+func (pq *PriorityQueue[T]) load(items []*Item[T]) {
+	if pq.fixedCap && len(items) <= cap(pq.items) {
+		// WithHardCapacity: refill the slice allocated up front.
+		clear(pq.items)
+		pq.items = pq.items[:len(items)]
+	} else {
+		pq.items = make([]*Item[T], len(items))
+	}
+	if pq.byValue != nil {
+		pq.byValue = pq.byValue.fresh(len(items))
+	}
+	for i, item := range items {
+		pq.seq++
+		item.seq = pq.seq
+		item.priority = pq.clamp(item.priority)
+		pq.items[i] = item
+		pq.indexAdd(item)
+	}
+	pq.expRebuild()
+	pq.Init()
+}
+
+// loadBounded is load for a queue that may be bounded: it returns an error
+// wrapping ErrQueueFull, leaving the queue unchanged, if items exceed the
+// WithMaxSize or WithHardCapacity limit.
+func (pq *PriorityQueue[T]) loadBounded(items []*Item[T]) error {
+	if pq.maxSize > 0 && len(items) > pq.maxSize {
+		return fmt.Errorf("%w: %d items for a limit of %d", ErrQueueFull, len(items), pq.maxSize)
+	}
+	pq.load(items)
+	return nil
+}
+
+func (pq *PriorityQueue[T]) Len() int { return len(pq.items) }
+
+// IsEmpty reports whether the queue holds no items.
+func (pq *PriorityQueue[T]) IsEmpty() bool { return len(pq.items) == 0 }
+
+// Cap returns the capacity of the queue's backing slice.
+func (pq *PriorityQueue[T]) Cap() int { return cap(pq.items) }
+
+// Height returns the number of levels in the heap: floor(log2(Len))+1, or 0
+// for an empty queue. A Pop sifts through at most Height-1 levels.
+func (pq *PriorityQueue[T]) Height() int { return bits.Len(uint(len(pq.items))) }
 
-func (pq PriorityQueue) Less(i, j int) bool {
-	// We want Pop to give us the highest, not lowest, priority so we use greater than here.
-	return pq[i].priority > pq[j].priority
+func (pq *PriorityQueue[T]) Less(i, j int) bool {
+	return pq.before(pq.items[i], pq.items[j])
 }
 
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
+// SetComparator replaces the queue's ordering with less (nil means
+// MinPriority) and re-heapifies under it, which costs O(n).
+func (pq *PriorityQueue[T]) SetComparator(less func(a, b *Item[T]) bool) {
+	pq.less, pq.order = less, orderOf(less)
+	heap.Init(pq)
 }
 
-func (pq *PriorityQueue) Push(x interface{}) {
-	n := len(*pq)
-	item := x.(*Item)
+// comparator returns the queue's ordering, defaulting to MinPriority.
+func (pq *PriorityQueue[T]) comparator() func(a, b *Item[T]) bool {
+	if pq.less == nil {
+		return MinPriority[T]
+	}
+	return pq.less
+}
+
+// before reports whether a pops before b, falling back to insertion order
+// for items the comparator considers equal.
+func (pq *PriorityQueue[T]) before(a, b *Item[T]) bool {
+	less := pq.comparator()
+	if less(a, b) {
+		return true
+	}
+	if less(b, a) {
+		return false
+	}
+	// Equal priorities pop in insertion order.
+	return a.seq < b.seq
+}
+
+func (pq *PriorityQueue[T]) Swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
+}
+
+// Push appends x, which must be an *Item[T], for heap.Push, which then sifts
+// it into place. Called directly it breaks the heap order; use PushItem.
+func (pq *PriorityQueue[T]) Push(x interface{}) {
+	n := len(pq.items)
+	item, ok := x.(*Item[T])
+	if !ok {
+		panic(fmt.Sprintf("priority queue %s: Push of %T, want %T, with Len %d", pq.label(), x, item, n))
+	}
+	pq.seq++
 	item.index = n
-	*pq = append(*pq, item)
+	item.seq = pq.seq
+	item.priority = pq.clamp(item.priority)
+	pq.items = append(pq.items, item)
+	pq.indexAdd(item)
+	pq.pushes++
+	pq.maxLen = max(pq.maxLen, len(pq.items))
+	pq.boundsAdded(item)
+	pq.expAdd(item)
+	pq.logOp("push", item)
 }
 
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
+// Pop removes and returns the last slot's item for heap.Pop, which first
+// swaps the top there. Called directly it returns an arbitrary item; use
+// PopItem.
+func (pq *PriorityQueue[T]) Pop() interface{} {
+	old := pq.items
 	n := len(old)
 	item := old[n-1]
 	item.index = -1 // for safety
-	*pq = old[0 : n-1]
+	pq.items = old[0 : n-1]
+	pq.maybeShrink()
+	pq.indexDelete(item)
+	pq.pops++
+	pq.boundsRemoved(item)
+	pq.expDelete(item)
+	if pq.removing {
+		pq.removing = false
+		pq.logOp("remove", item)
+	} else {
+		pq.logOp("pop", item)
+	}
+	if pq.deferRemoved {
+		pq.deferRemoved = false
+		return item
+	}
+	// The heap is already fixed by the time container/heap calls Pop, so the
+	// hook may safely use the queue.
+	pq.removed(item)
 	return item
 }
 
-// update modifies the priority of an item and updates the heap accordingly
-func (pq *PriorityQueue) Update(item *Item, priority int64) {
+// shrinkMinCap is the capacity below which WithShrinkOnPop leaves the
+// backing slice alone, so small queues do not churn allocations.
+const shrinkMinCap = 64
+
+// maybeShrink halves the backing slice's spare room, per WithShrinkOnPop,
+// once Len has fallen below the configured fraction of capacity.
+func (pq *PriorityQueue[T]) maybeShrink() {
+	n := len(pq.items)
+	if pq.shrinkBelow == 0 || cap(pq.items) <= shrinkMinCap || float64(n) >= pq.shrinkBelow*float64(cap(pq.items)) {
+		return
+	}
+	items := make([]*Item[T], n, max(2*n, shrinkMinCap))
+	copy(items, pq.items)
+	pq.items = items
+}
+
+// removed runs the item's cancel func and the OnRemove hook, if any, for an
+// item that left the queue.
+func (pq *PriorityQueue[T]) removed(item *Item[T]) {
+	pq.removedWith(item, takeCancel(item))
+}
+
+// removedWith is removed for an item whose cancel func the caller already
+// took with takeCancel.
+func (pq *PriorityQueue[T]) removedWith(item *Item[T], cancel func()) {
+	if cancel != nil {
+		cancel()
+	}
+	if pq.onRemove != nil {
+		pq.onRemove(item)
+	}
+}
+
+// takeCancel clears and returns item's cancel func, if any.
+func takeCancel[T any](item *Item[T]) func() {
+	if item.ext == nil {
+		return nil
+	}
+	cancel := item.ext.cancel
+	item.ext.cancel = nil
+	return cancel
+}
+
+// indexAdd records item under its value in the value index, if enabled.
+func (pq *PriorityQueue[T]) indexAdd(item *Item[T]) {
+	if pq.byValue != nil {
+		pq.byValue.add(item)
+	}
+}
+
+// indexDelete drops item from the value index, if enabled.
+func (pq *PriorityQueue[T]) indexDelete(item *Item[T]) {
+	if pq.byValue != nil {
+		pq.byValue.remove(item)
+	}
+}
+
+// Compact rebuilds the value index from the queued items, dropping any stale
+// entries, and resets every item's index to its slot. Go maps never shrink,
+// so for a long-lived queue with heavy churn this also releases the memory
+// the old index was holding.
+func (pq *PriorityQueue[T]) Compact() {
+	for i, item := range pq.items {
+		item.index = i
+	}
+	if pq.byValue == nil {
+		return
+	}
+	pq.byValue = pq.byValue.fresh(len(pq.items))
+	// Add in push order, so duplicates are listed as indexAdd would list them.
+	for _, item := range slices.SortedFunc(slices.Values(pq.items), bySeq[T]) {
+		pq.indexAdd(item)
+	}
+}
+
+// lookup returns every queued item holding value, in push order. It uses
+// the value index when enabled and falls back to an O(n) scan otherwise.
+func (pq *PriorityQueue[T]) lookup(value T) []*Item[T] {
+	if pq.byValue != nil {
+		return pq.byValue.get(value)
+	}
+	var found []*Item[T]
+	key := any(value)
+	for _, item := range pq.items {
+		if any(item.value) == key {
+			found = append(found, item)
+		}
+	}
+	if len(found) > 1 {
+		slices.SortFunc(found, bySeq[T])
+	}
+	return found
+}
+
+// valueKey returns what the by-value methods match value by: its key under
+// WithValueIndexBy, otherwise the value itself.
+func (pq *PriorityQueue[T]) valueKey(value T) any {
+	if pq.byValue != nil {
+		return pq.byValue.key(value)
+	}
+	return value
+}
+
+// PushItem pushes item onto the heap. It returns false, leaving the queue
+// unchanged, if the queue was built WithMaxSize and is already full, or if
+// item is nil or already in the queue.
+func (pq *PriorityQueue[T]) PushItem(item *Item[T]) bool {
+	defer pq.rethrow("PushItem", item)
+	if !pq.admits(item) {
+		return false
+	}
+	item.priority = pq.jittered(item)
+	heap.Push(pq, item)
+	return true
+}
+
+// admits reports whether PushItem would accept item: it is not nil, not
+// already queued, and the queue has room for it.
+func (pq *PriorityQueue[T]) admits(item *Item[T]) bool {
+	return item != nil && !pq.owns(item) && (pq.maxSize == 0 || len(pq.items) < pq.maxSize)
+}
+
+// jittered returns item's priority plus the WithJitter offset, if any. Only
+// an item that has never been queued is jittered: one coming back through
+// Requeue or Restore keeps the priority it was given.
+func (pq *PriorityQueue[T]) jittered(item *Item[T]) int64 {
+	if pq.jitter == nil || item.seq != 0 {
+		return item.priority
+	}
+	return saturatingAdd(item.priority, pq.jitter.Int64N(pq.jitterMax+1))
+}
+
+// clamp brings priority into the range set by WithPriorityRange, counting
+// each value it has to change.
+func (pq *PriorityQueue[T]) clamp(priority int64) int64 {
+	if !pq.clampRange {
+		return priority
+	}
+	switch {
+	case priority < pq.clampMin:
+		pq.clamped++
+		return pq.clampMin
+	case priority > pq.clampMax:
+		pq.clamped++
+		return pq.clampMax
+	}
+	return priority
+}
+
+// PushAllCrossover is the batch-size ratio at which PushAll switches from
+// pushing items one by one, O(k log n), to appending all of them and
+// re-heapifying once, O(n+k): it heapifies when
+// k >= PushAllCrossover*n for a batch of k items into a queue of n. Pushing
+// one item only sifts it a level or two on average, so heapifying pays off
+// only for batches well above the queue's size; BenchmarkPushAll measures
+// the crossover.
+const PushAllCrossover = 2
+
+// PushAll pushes every item in items and returns how many were pushed. Like
+// PushItem, it skips nil items and items already in the queue, and it stops
+// once the queue is full (see WithMaxSize). Large batches are appended and
+// heapified once; small ones are pushed individually, as decided by
+// PushAllCrossover.
+func (pq *PriorityQueue[T]) PushAll(items []*Item[T]) int {
+	room := len(items)
+	if pq.maxSize > 0 {
+		room = max(pq.maxSize-len(pq.items), 0)
+	}
+	if len(items) < PushAllCrossover*len(pq.items) {
+		return pq.pushEach(items, room)
+	}
+	return pq.pushHeapify(items, room)
+}
+
+// pushEach is PushAll's strategy for small batches. It pushes at most room
+// items.
+func (pq *PriorityQueue[T]) pushEach(items []*Item[T], room int) int {
+	n := 0
+	for _, item := range items {
+		if n == room {
+			break
+		}
+		if pq.PushItem(item) {
+			n++
+		}
+	}
+	return n
+}
+
+// pushHeapify is PushAll's strategy for large batches. It pushes at most
+// room items. Each appended item's index is set at once, so a later
+// duplicate in the same batch is seen as queued.
+func (pq *PriorityQueue[T]) pushHeapify(items []*Item[T], room int) int {
+	defer pq.rethrow("PushAll", nil)
+	start := len(pq.items)
+	pq.items = slices.Grow(pq.items, min(len(items), room))
+	for _, item := range items {
+		if len(pq.items)-start == room {
+			break
+		}
+		if item == nil || pq.owns(item) {
+			continue
+		}
+		item.priority = pq.jittered(item)
+		pq.seq++
+		item.seq = pq.seq
+		item.index = len(pq.items)
+		item.priority = pq.clamp(item.priority)
+		pq.items = append(pq.items, item)
+		pq.indexAdd(item)
+		pq.expAdd(item)
+		pq.logOp("push", item)
+	}
+	n := len(pq.items) - start
+	pq.pushes += uint64(n)
+	pq.Init()
+	return n
+}
+
+// PopItem removes and returns the top item. ok is false when the queue is
+// empty. This is the preferred way to pop; the Pop method exists for
+// container/heap.
+func (pq *PriorityQueue[T]) PopItem() (*Item[T], bool) {
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	defer pq.rethrow("PopItem", pq.items[0])
+	return heap.Pop(pq).(*Item[T]), true
+}
+
+// PopIf pops the top item only if pred returns true for it. ok is false, and
+// the queue is unchanged, when pred rejects the top or the queue is empty;
+// pred is not called on an empty queue. pred must not modify the item.
+func (pq *PriorityQueue[T]) PopIf(pred func(*Item[T]) bool) (*Item[T], bool) {
+	if len(pq.items) == 0 || !pred(pq.items[0]) {
+		return nil, false
+	}
+	return heap.Pop(pq).(*Item[T]), true
+}
+
+// Requeue gives a popped or removed item the new priority and pushes the
+// same *Item again, so a steady set of keys can cycle through the queue
+// without allocating. It returns ErrItemQueued, changing nothing, if item is
+// still in the queue, and ErrQueueFull if the queue has no room.
+func (pq *PriorityQueue[T]) Requeue(item *Item[T], priority int64) error {
+	if pq.owns(item) {
+		return ErrItemQueued
+	}
+	if pq.maxSize > 0 && len(pq.items) >= pq.maxSize {
+		return ErrQueueFull
+	}
 	item.priority = priority
+	pq.PushItem(item)
+	return nil
+}
+
+// Handle refers to an item pushed with PushValue. Unlike a raw *Item, it
+// knows when the item has left the queue, so a stale Handle is rejected by
+// UpdateHandle rather than corrupting the heap. The zero Handle refers to
+// nothing.
+type Handle[T any] struct {
+	item *Item[T]
+	// seq is the item's sequence number at push time; re-pushing the item
+	// gives it a new one, which invalidates older handles.
+	seq uint64
+}
+
+// Item returns the item the handle refers to, or nil for the zero Handle.
+func (h Handle[T]) Item() *Item[T] { return h.item }
+
+// PopFairTie pops one of the items tied with the top, choosing the one that
+// PopFairTie popped least recently (items it never popped first, then by
+// insertion order). Re-pushing popped items and calling PopFairTie again
+// therefore rotates through a tied group instead of favouring whichever item
+// the heap surfaces. Finding the tie group scans the whole array. ok is
+// false when the queue is empty.
+func (pq *PriorityQueue[T]) PopFairTie() (*Item[T], bool) {
+	ties := pq.PeekTies()
+	if len(ties) == 0 {
+		return nil, false
+	}
+	pick := ties[0]
+	for _, item := range ties[1:] {
+		if lastPopped(item) < lastPopped(pick) {
+			pick = item
+		}
+	}
+	heap.Remove(pq, pick.index)
+	pq.fairPops++
+	pick.extended().lastPopped = pq.fairPops
+	return pick, true
+}
+
+// lastPopped returns the PopFairTie stamp of item, 0 if it was never popped
+// that way.
+func lastPopped[T any](item *Item[T]) uint64 {
+	if item.ext == nil {
+		return 0
+	}
+	return item.ext.lastPopped
+}
+
+// PushValue wraps value and priority in a new Item, pushes it and returns a
+// Handle to it for later use with UpdateHandle. It returns the zero Handle
+// if the queue is full (see WithMaxSize).
+func (pq *PriorityQueue[T]) PushValue(value T, priority int64) Handle[T] {
+	item := NewItem(value, priority)
+	if !pq.PushItem(item) {
+		return Handle[T]{}
+	}
+	return Handle[T]{item: item, seq: item.seq}
+}
+
+// UpdateHandle changes the priority of the item behind h. It returns false,
+// leaving the queue untouched, if the item has already left the queue.
+func (pq *PriorityQueue[T]) UpdateHandle(h Handle[T], priority int64) bool {
+	if h.item == nil || h.item.seq != h.seq {
+		return false
+	}
+	return pq.Update(h.item, priority)
+}
+
+// PushOrUpdate deduplicates pushes by value: if an item holding value is
+// already queued its priority is updated, otherwise a new item is pushed.
+// It returns the existing or new item, or nil if a new item was needed but
+// the queue is full. Lookups are O(1) with WithValueIndex and O(n) without.
+func (pq *PriorityQueue[T]) PushOrUpdate(value T, priority int64) *Item[T] {
+	if items := pq.lookup(value); len(items) > 0 {
+		pq.Update(items[0], priority)
+		return items[0]
+	}
+	item := NewItem(value, priority)
+	if !pq.PushItem(item) {
+		return nil
+	}
+	return item
+}
+
+// ReplaceTop pops the top item and pushes item in its place with a single
+// O(log n) sift-down, which is cheaper than heap.Pop followed by heap.Push.
+// It returns the old top, or nil after simply pushing item onto an empty
+// queue. item gets the same jitter and clamping as with PushItem. A nil or
+// already queued item is rejected: the queue is left unchanged and
+// ReplaceTop returns nil.
+func (pq *PriorityQueue[T]) ReplaceTop(item *Item[T]) *Item[T] {
+	if item == nil || pq.owns(item) {
+		return nil
+	}
+	if len(pq.items) == 0 {
+		pq.PushItem(item)
+		return nil
+	}
+	defer pq.rethrow("ReplaceTop", item)
+	old := pq.items[0]
+	old.index = -1 // for safety
+	pq.indexDelete(old)
+	item.priority = pq.jittered(item)
+	pq.seq++
+	item.index = 0
+	item.seq = pq.seq
+	item.priority = pq.clamp(item.priority)
+	pq.items[0] = item
+	pq.indexAdd(item)
+	pq.pushes++
+	pq.pops++
+	pq.boundsRemoved(old)
+	pq.boundsAdded(item)
+	pq.expDelete(old)
+	pq.expAdd(item)
+	pq.logOp("pop", old)
+	pq.logOp("push", item)
+	heap.Fix(pq, 0)
+	pq.removed(old)
+	return old
+}
+
+// update modifies the priority of an item and updates the heap accordingly.
+// It returns false, leaving both the item and the heap untouched, if the item
+// is not in this queue (for example because it was already popped). An
+// update to the item's current priority returns true without touching the
+// heap, so it cannot repair an item changed with SetPriority; use
+// FixByValue for that.
+func (pq *PriorityQueue[T]) Update(item *Item[T], priority int64) bool {
+	defer pq.rethrow("Update", item)
+	if !pq.owns(item) {
+		return false
+	}
+	old := item.priority
+	if item.priority = pq.clamp(priority); item.priority == old {
+		return true
+	}
+	pq.boundsUpdated(item, old)
+	pq.logOp("update", item)
 	// NOTE: fix is a slightly more efficient version of calling Remove() and
 	// then Push()
 	heap.Fix(pq, item.index)
+	return true
+}
+
+// Adjust adds delta to item's current priority and restores the heap order.
+// The sum saturates at math.MaxInt64 and math.MinInt64 instead of wrapping.
+// Like Update, it returns false if item is not in the queue.
+func (pq *PriorityQueue[T]) Adjust(item *Item[T], delta int64) bool {
+	return pq.Update(item, saturatingAdd(item.priority, delta))
+}
+
+// saturatingAdd returns a+b clamped to the int64 range.
+func saturatingAdd(a, b int64) int64 {
+	switch {
+	case b > 0 && a > math.MaxInt64-b:
+		return math.MaxInt64
+	case b < 0 && a < math.MinInt64-b:
+		return math.MinInt64
+	}
+	return a + b
+}
+
+// UpdateBatch applies every priority in changes and then re-heapifies once
+// with heap.Init. Items that are not in the queue are skipped. Init costs
+// O(n) regardless of how many items changed, while k calls to Update cost
+// O(k log n), so the batch wins roughly once k exceeds n/log2(n).
+func (pq *PriorityQueue[T]) UpdateBatch(changes map[*Item[T]]int64) {
+	defer pq.rethrow("UpdateBatch", nil)
+	for item, priority := range changes {
+		if !pq.owns(item) {
+			continue
+		}
+		if priority = pq.clamp(priority); priority != item.priority {
+			item.priority = priority
+			pq.logOp("update", item)
+		}
+	}
+	pq.Init()
+}
+
+// MapPriorities replaces every item's priority p with fn(p), calling fn
+// exactly once per item, and then re-heapifies once with heap.Init. fn need
+// not preserve order; it must not touch the queue.
+func (pq *PriorityQueue[T]) MapPriorities(fn func(old int64) int64) {
+	for _, item := range pq.items {
+		if p := pq.clamp(fn(item.priority)); p != item.priority {
+			item.priority = p
+			pq.logOp("update", item)
+		}
+	}
+	pq.Init()
+}
+
+// owns reports whether item currently sits in this queue. A nil item never
+// does.
+func (pq *PriorityQueue[T]) owns(item *Item[T]) bool {
+	return item != nil && item.index >= 0 && item.index < len(pq.items) && pq.items[item.index] == item
+}
+
+// UpdateByValue changes the priority of the item holding value, for callers
+// that only have the value at hand. When value is queued more than once it
+// updates the first occurrence, the earliest pushed; use UpdateByValueAt for
+// another. It returns false if value is not queued.
+func (pq *PriorityQueue[T]) UpdateByValue(value T, priority int64) bool {
+	return pq.UpdateByValueAt(value, 0, priority)
+}
+
+// UpdateByValueAt is UpdateByValue for the occurrence'th item holding value,
+// counting from 0 in push order. It returns false if there is no such
+// occurrence.
+func (pq *PriorityQueue[T]) UpdateByValueAt(value T, occurrence int, priority int64) bool {
+	item, ok := pq.GetByValueAt(value, occurrence)
+	if !ok {
+		return false
+	}
+	return pq.Update(item, priority)
+}
+
+// FixByValue restores the heap order around the items holding value after
+// their priority was changed out-of-band, for example with SetPriority. It
+// returns false if value is not queued. heap.Fix assumes a single slot is out
+// of place, so a lone item is fixed in O(log n) but several items holding
+// value are repaired by re-heapifying in O(n).
+func (pq *PriorityQueue[T]) FixByValue(value T) bool {
+	items := pq.lookup(value)
+	for _, item := range items {
+		pq.logOp("update", item)
+	}
+	switch len(items) {
+	case 0:
+		return false
+	case 1:
+		pq.minItem, pq.maxItem = nil, nil
+		heap.Fix(pq, items[0].index)
+	default:
+		pq.Init()
+	}
+	return true
+}
+
+// Contains reports whether an item holding value is queued. It runs in O(1)
+// with WithValueIndex and O(n) without.
+func (pq *PriorityQueue[T]) Contains(value T) bool {
+	return len(pq.lookup(value)) > 0
+}
+
+// GetByValue returns the queued item holding value, in O(1) with
+// WithValueIndex and O(n) without. Like UpdateByValue it picks the first
+// occurrence of a duplicated value. ok is false if value is not queued.
+func (pq *PriorityQueue[T]) GetByValue(value T) (*Item[T], bool) {
+	return pq.GetByValueAt(value, 0)
+}
+
+// GetByValueAt returns the occurrence'th item holding value, counting from 0
+// in push order. ok is false if there is no such occurrence.
+func (pq *PriorityQueue[T]) GetByValueAt(value T, occurrence int) (*Item[T], bool) {
+	items := pq.lookup(value)
+	if occurrence < 0 || occurrence >= len(items) {
+		return nil, false
+	}
+	return items[occurrence], true
+}
+
+// GetAllByValue returns every queued item holding value, in push order, or
+// nil if there are none. The slice is the caller's to keep.
+func (pq *PriorityQueue[T]) GetAllByValue(value T) []*Item[T] {
+	return slices.Clone(pq.lookup(value))
+}
+
+// Remove deletes item from the queue wherever it sits in the heap. It returns
+// false if the item has already been popped or does not belong to this queue.
+func (pq *PriorityQueue[T]) Remove(item *Item[T]) bool {
+	defer pq.rethrow("Remove", item)
+	if !pq.owns(item) {
+		return false
+	}
+	pq.removing = true
+	heap.Remove(pq, item.index)
+	// container/heap finishes a removal with Pop, which counted it as a pop.
+	pq.pops--
+	pq.removes++
+	return true
+}
+
+// PopExpired pops every item whose priority is at or past threshold in the
+// queue's order (priority <= threshold for the default min-heap, >= for
+// MaxPriority) and returns them in pop order. An empty queue or a threshold
+// that matches nothing returns nil and leaves the heap untouched.
+func (pq *PriorityQueue[T]) PopExpired(threshold int64) []*Item[T] {
+	var expired []*Item[T]
+	desc := pq.descending()
+	for pq.topExpired(threshold, desc) {
+		expired = append(expired, heap.Pop(pq).(*Item[T]))
+	}
+	return expired
+}
+
+// DrainBatch pops up to maxItems items in pop order, stopping early once the
+// top is past maxPriority in the queue's order (priority > maxPriority for
+// the default min-heap). The rest stay queued, so calling it again next tick
+// resumes exactly where this call stopped. It returns nil if nothing was
+// popped.
+func (pq *PriorityQueue[T]) DrainBatch(maxItems int, maxPriority int64) []*Item[T] {
+	var batch []*Item[T]
+	desc := pq.descending()
+	for len(batch) < maxItems && pq.topExpired(maxPriority, desc) {
+		batch = append(batch, heap.Pop(pq).(*Item[T]))
+	}
+	return batch
+}
+
+// RemoveExpired removes and returns, in expiry order, every item whose
+// expiry is at or before nowMillis. Items without an expiry are kept. Expiry
+// is unrelated to heap order, so the queue keeps a second heap of the
+// expiring items ordered by expiry: RemoveExpired reads only the k expired
+// items from it and removes each from both heaps, O(k log n) in all, and
+// touches nothing when no item has expired. It returns nil if nothing
+// expired.
+func (pq *PriorityQueue[T]) RemoveExpired(nowMillis int64) []*Item[T] {
+	var expired []*Item[T]
+	for len(pq.expiring) > 0 && pq.expiring[0].ext.expiry <= nowMillis {
+		item := pq.expiring[0]
+		pq.Remove(item)
+		expired = append(expired, item)
+	}
+	return expired
+}
+
+// RemoveWhere removes and returns every item for which pred returns true,
+// re-heapifying once at the end instead of fixing the heap per removal. pred
+// must not modify the items. If nothing matches, RemoveWhere returns nil and
+// the queue is untouched.
+func (pq *PriorityQueue[T]) RemoveWhere(pred func(*Item[T]) bool) []*Item[T] {
+	return pq.extract(pred)
+}
+
+// RemoveByValues removes every item holding any of values, compacting the
+// array in one pass and re-heapifying once, and returns how many items were
+// removed. Values that are not queued are skipped. The whole removal costs
+// O(n + k) for k values, against O(k log n) for k calls to Remove, so it
+// pays off once k is a sizeable fraction of n.
+func (pq *PriorityQueue[T]) RemoveByValues(values []T) int {
+	doomed := make(map[any]struct{}, len(values))
+	for _, v := range values {
+		doomed[pq.valueKey(v)] = struct{}{}
+	}
+	return len(pq.extract(func(item *Item[T]) bool {
+		_, ok := doomed[pq.valueKey(item.value)]
+		return ok
+	}))
+}
+
+// TrimToSmallest keeps only the n items that would pop first, which for the
+// default min-heap are the n smallest priorities, and removes and returns
+// the rest in array order. The survivors are found by walking the heap
+// frontier in O(n log n) for the n kept, then the others are dropped in one
+// O(Len) pass, with no full sort. n <= 0 empties the queue, returning every
+// item; n >= Len leaves it unchanged and returns nil.
+func (pq *PriorityQueue[T]) TrimToSmallest(n int) []*Item[T] {
+	if n >= len(pq.items) {
+		return nil
+	}
+	keep := make(map[*Item[T]]struct{}, max(n, 0))
+	for _, item := range pq.PeekN(n) {
+		keep[item] = struct{}{}
+	}
+	return pq.extract(func(item *Item[T]) bool {
+		_, ok := keep[item]
+		return !ok
+	})
+}
+
+// extract removes every item matching pred in a single pass and then
+// re-heapifies once. It returns the removed items in array order.
+func (pq *PriorityQueue[T]) extract(pred func(*Item[T]) bool) []*Item[T] {
+	var removed []*Item[T]
+	kept := pq.items[:0]
+	for _, item := range pq.items {
+		if !pred(item) {
+			kept = append(kept, item)
+			continue
+		}
+		item.index = -1 // for safety
+		pq.indexDelete(item)
+		pq.expDelete(item)
+		pq.logOp("remove", item)
+		removed = append(removed, item)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	clear(pq.items[len(kept):])
+	pq.items = kept
+	pq.removes += uint64(len(removed))
+	pq.Init()
+	for _, item := range removed {
+		pq.removed(item)
+	}
+	return removed
+}
+
+// DrainSorted pops every item and returns them in pop order, leaving the
+// queue empty. Draining an empty queue returns an empty, non-nil slice.
+func (pq *PriorityQueue[T]) DrainSorted() []*Item[T] {
+	items := make([]*Item[T], 0, len(pq.items))
+	for len(pq.items) > 0 {
+		items = append(items, heap.Pop(pq).(*Item[T]))
+	}
+	return items
+}
+
+// DrainFunc hands every item to fn in pop order, popping each one once fn
+// returns nil for it. If fn returns an error, DrainFunc stops and returns it,
+// leaving that item and everything after it queued, so the failed item can be
+// retried. fn sees the item while it is still the queue's top and must not
+// modify the queue.
+func (pq *PriorityQueue[T]) DrainFunc(fn func(*Item[T]) error) error {
+	for len(pq.items) > 0 {
+		if err := fn(pq.items[0]); err != nil {
+			return err
+		}
+		heap.Pop(pq)
+	}
+	return nil
+}
+
+// DrainToChannel sends every item to ch in pop order, blocking while ch is
+// full so a slow consumer applies backpressure. Each item is popped before it
+// is sent, so the receiver gets an item the queue no longer touches, and put
+// back if ctx is done before ch accepts it: DrainToChannel then returns
+// ctx.Err() with every unsent item still queued, in its original place. The
+// op log records such an item as popped and pushed again. An item's cancel
+// func and the OnRemove hook run only once ch has accepted it, so they may
+// run while the receiver is already using the item. It never closes ch. The
+// queue is not goroutine-safe: nothing else may use it until DrainToChannel
+// returns.
+func (pq *PriorityQueue[T]) DrainToChannel(ctx context.Context, ch chan<- *Item[T]) error {
+	for len(pq.items) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pq.deferRemoved = true
+		item := heap.Pop(pq).(*Item[T])
+		// Once sent, the item is the receiver's; take what removed needs
+		// from it first.
+		cancel := takeCancel(item)
+		select {
+		case ch <- item:
+			pq.removedWith(item, cancel)
+		case <-ctx.Done():
+			if cancel != nil {
+				item.ext.cancel = cancel
+			}
+			pq.unpop(item)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// unpop puts back an item popped without its removal hooks, keeping its
+// sequence number so that it regains its place among equal priorities.
+func (pq *PriorityQueue[T]) unpop(item *Item[T]) {
+	item.index = len(pq.items)
+	pq.items = append(pq.items, item)
+	pq.indexAdd(item)
+	pq.pops--
+	pq.boundsAdded(item)
+	pq.expAdd(item)
+	pq.logOp("push", item)
+	heap.Fix(pq, item.index)
+}
+
+// topExpired reports whether the queue's top item is at or past threshold in
+// the direction given by descending.
+func (pq *PriorityQueue[T]) topExpired(threshold int64, desc bool) bool {
+	return len(pq.items) > 0 && reached(pq.items[0].priority, threshold, desc)
+}
+
+// reached reports whether priority is at or past threshold: at or below it
+// for a queue that pops low priorities first, at or above it otherwise.
+func reached(priority, threshold int64, desc bool) bool {
+	if desc {
+		return priority >= threshold
+	}
+	return priority <= threshold
+}
+
+// descending reports whether the queue pops higher priorities first, as
+// under MaxPriority. The comparator may look past the priority, at values or
+// weights, so it is asked only about two copies of the top item that differ
+// in priority alone. An empty queue, or one on the default comparator, is
+// ascending.
+func (pq *PriorityQueue[T]) descending() bool {
+	if pq.less == nil || len(pq.items) == 0 {
+		return false
+	}
+	lo, hi := *pq.items[0], *pq.items[0]
+	lo.priority, hi.priority = 0, 1
+	return pq.less(&hi, &lo)
+}
+
+// PurgeExpired pops expired items one at a time, as PopExpired would, passing
+// each to onPurged (if non-nil). ctx is checked before every pop, so a long
+// sweep can be cancelled and resumed later: it then returns the number
+// purged so far and ctx.Err(), with the remaining items still a valid heap.
+func (pq *PriorityQueue[T]) PurgeExpired(ctx context.Context, threshold int64, onPurged func(*Item[T])) (purged int, err error) {
+	desc := pq.descending()
+	for pq.topExpired(threshold, desc) {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		item := heap.Pop(pq).(*Item[T])
+		purged++
+		if onPurged != nil {
+			onPurged(item)
+		}
+	}
+	return purged, nil
+}
+
+// Clear removes every item while keeping the backing slice's capacity, so a
+// long-lived queue can be drained and refilled without reallocating. Each
+// removed item's index is reset to -1, and its cancel func and the OnRemove
+// hook run for it once the queue is empty.
+func (pq *PriorityQueue[T]) Clear() {
+	// The hooks may push, so keep the items out of the reused slice.
+	removed := slices.Clone(pq.items)
+	pq.removes += uint64(len(pq.items))
+	for i, item := range pq.items {
+		item.index = -1
+		pq.logOp("remove", item)
+		pq.items[i] = nil
+	}
+	pq.items = pq.items[:0]
+	pq.minItem, pq.maxItem = nil, nil
+	clear(pq.expiring)
+	pq.expiring = pq.expiring[:0]
+	if pq.byValue != nil {
+		pq.byValue.clear()
+	}
+	for _, item := range removed {
+		pq.removed(item)
+	}
+}
+
+// Merge moves every item from other into pq and re-heapifies once with
+// heap.Init, which is O(n) rather than the O(n log n) of pushing them one by
+// one; with a value index, the k merged items are also sorted into push
+// order for it, O(k log k). Merged items tie-break after pq's own items.
+// other is left empty, since an item must not belong to two queues at once.
+//
+// If pq has a WithMaxSize or WithHardCapacity limit, only as many items as
+// fit are moved, those other would pop first; the rest stay in other.
+func (pq *PriorityQueue[T]) Merge(other *PriorityQueue[T]) {
+	if other == pq {
+		return
+	}
+	if pq.maxSize > 0 && len(pq.items)+len(other.items) > pq.maxSize {
+		pq.mergeSome(other, max(pq.maxSize-len(pq.items), 0))
+		return
+	}
+	pq.mergeAll(other, other.items)
+	other.items = nil
+	other.expiring = nil
+	other.minItem, other.maxItem = nil, nil
+	if other.byValue != nil {
+		other.byValue.clear()
+	}
+}
+
+// mergeSome is Merge moving only the first n items in other's pop order.
+func (pq *PriorityQueue[T]) mergeSome(other *PriorityQueue[T], n int) {
+	if n == 0 {
+		return
+	}
+	moved := make([]*Item[T], 0, n)
+	other.walk(func(item *Item[T]) bool {
+		moved = append(moved, item)
+		return len(moved) < n
+	})
+	for _, item := range moved {
+		other.indexDelete(item)
+		other.expDelete(item)
+		item.index = -1
+	}
+	other.items = slices.DeleteFunc(other.items, func(item *Item[T]) bool {
+		return item.index == -1
+	})
+	other.Init()
+	pq.mergeAll(other, moved)
+}
+
+// mergeAll appends moved, taken from other, to pq and re-heapifies.
+func (pq *PriorityQueue[T]) mergeAll(other *PriorityQueue[T], moved []*Item[T]) {
+	base, added := pq.seq, len(pq.items)
+	for _, item := range moved {
+		other.logOp("remove", item)
+		item.index = len(pq.items)
+		item.seq += base
+		item.priority = pq.clamp(item.priority)
+		pq.items = append(pq.items, item)
+		pq.logOp("push", item)
+	}
+	if pq.byValue != nil {
+		// The index lists duplicates in push order, which other's heap
+		// array does not follow.
+		for _, item := range slices.SortedFunc(slices.Values(pq.items[added:]), bySeq[T]) {
+			pq.indexAdd(item)
+		}
+	}
+	pq.seq += other.seq
+	pq.expRebuild()
+	pq.Init()
+}
+
+// Split moves pq's items into two new queues: due holds those at or past
+// threshold in the queue's order (priority <= threshold for the default
+// min-heap), as PopExpired would pop them, and notDue the rest. Each is built
+// in O(n) with the comparator, clock, name, value-index setting and
+// WithPriorityRange clamp of pq, and ties keep their relative pop order. The
+// halves have no size limit, OnRemove hook, op log or jitter. pq is left
+// empty.
+func (pq *PriorityQueue[T]) Split(threshold int64) (due, notDue *PriorityQueue[T]) {
+	desc := pq.descending()
+	var dueItems, notDueItems []*Item[T]
+	for _, item := range pq.items {
+		pq.logOp("remove", item)
+		if reached(item.priority, threshold, desc) {
+			dueItems = append(dueItems, item)
+		} else {
+			notDueItems = append(notDueItems, item)
+		}
+	}
+	due, notDue = pq.emptyLike(), pq.emptyLike()
+	slices.SortFunc(dueItems, bySeq[T])
+	slices.SortFunc(notDueItems, bySeq[T])
+	due.load(dueItems)
+	notDue.load(notDueItems)
+	pq.items = nil
+	pq.expiring = nil
+	pq.minItem, pq.maxItem = nil, nil
+	if pq.byValue != nil {
+		pq.byValue.clear()
+	}
+	return due, notDue
+}
+
+// emptyLike returns an empty queue with pq's comparator, clock, name,
+// value-index setting and priority clamp.
+func (pq *PriorityQueue[T]) emptyLike() *PriorityQueue[T] {
+	q := &PriorityQueue[T]{
+		less:  pq.less,
+		order: pq.order,
+		clock: pq.clock,
+		name:  pq.name,
+
+		clampRange: pq.clampRange,
+		clampMin:   pq.clampMin,
+		clampMax:   pq.clampMax,
+	}
+	if pq.byValue != nil {
+		q.byValue = pq.byValue.fresh(0)
+	}
+	return q
+}
+
+// Reserve grows the backing slice, with at most one allocation, so that n
+// more items can be pushed without reallocating. Items keep their slots and
+// indices. A negative n is treated as 0, so nothing is allocated.
+func (pq *PriorityQueue[T]) Reserve(n int) {
+	pq.items = slices.Grow(pq.items, max(n, 0))
+}
+
+// ShrinkToFit reallocates the backing slice so its capacity equals Len,
+// releasing memory left over from a burst. Items keep their slots, so their
+// indices are unchanged. It is a no-op when the spare capacity is within a
+// quarter of Len.
+func (pq *PriorityQueue[T]) ShrinkToFit() {
+	n := len(pq.items)
+	if cap(pq.items)-n <= n/4 {
+		return
+	}
+	items := make([]*Item[T], n)
+	copy(items, pq.items)
+	pq.items = items
+}
+
+// Clone returns an independent copy of the queue. Every Item is copied into a
+// new pointer, so updates to either queue never show through in the other.
+// The clone keeps the comparator and the WithPriorityRange clamp but not the
+// OnRemove hook, the op log, the jitter or the items' cancel funcs. It costs
+// O(n) time and memory.
+func (pq *PriorityQueue[T]) Clone() *PriorityQueue[T] {
+	clone := &PriorityQueue[T]{
+		items:   make([]*Item[T], len(pq.items), cap(pq.items)),
+		less:    pq.less,
+		order:   pq.order,
+		seq:     pq.seq,
+		maxSize: pq.maxSize,
+		clock:   pq.clock,
+
+		fixedCap: pq.fixedCap,
+		name:     pq.name,
+
+		shrinkBelow: pq.shrinkBelow,
+		clampRange:  pq.clampRange,
+		clampMin:    pq.clampMin,
+		clampMax:    pq.clampMax,
+	}
+	for i, item := range pq.items {
+		copied := *item
+		if item.ext != nil {
+			ext := *item.ext
+			ext.meta = maps.Clone(ext.meta)
+			ext.cancel = nil
+			copied.ext = &ext
+		}
+		clone.items[i] = &copied
+	}
+	if pq.byValue != nil {
+		clone.byValue = pq.byValue.remap(func(item *Item[T]) *Item[T] {
+			return clone.items[item.index]
+		})
+	}
+	if len(pq.expiring) > 0 {
+		clone.expiring = make(expiryHeap[T], len(pq.expiring))
+		for i, item := range pq.expiring {
+			clone.expiring[i] = clone.items[item.index]
+		}
+	}
+	return clone
+}
+
+// Peek returns the heap's top item without removing it. ok is false when the
+// queue is nil or empty.
+func (pq *PriorityQueue[T]) Peek() (*Item[T], bool) {
+	if pq == nil || len(pq.items) == 0 {
+		return nil, false
+	}
+	return pq.items[0], true
+}
+
+// PeekPriority returns the priority of the heap's top item. ok is false when
+// the queue is nil or empty.
+func (pq *PriorityQueue[T]) PeekPriority() (int64, bool) {
+	item, ok := pq.Peek()
+	if !ok {
+		return 0, false
+	}
+	return item.priority, true
+}
+
+// PeekN returns up to n items in pop order without removing them. If n is at
+// least Len, every item is returned in order. The queue is not modified.
+func (pq *PriorityQueue[T]) PeekN(n int) []*Item[T] {
+	if n > pq.Len() {
+		n = pq.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	items := make([]*Item[T], 0, n)
+	pq.walk(func(item *Item[T]) bool {
+		items = append(items, item)
+		return len(items) < n
+	})
+	return items
+}
+
+// ToSlices exports the queue as parallel slices of values and priorities in
+// pop order, the inverse of FromSlices. The queue is not modified.
+func (pq *PriorityQueue[T]) ToSlices() (values []T, priorities []int64) {
+	values = make([]T, 0, pq.Len())
+	priorities = make([]int64, 0, pq.Len())
+	pq.walk(func(item *Item[T]) bool {
+		values = append(values, item.value)
+		priorities = append(priorities, item.priority)
+		return true
+	})
+	return values, priorities
+}
+
+// PeekTies returns every item that ties with the top item under the queue's
+// comparator, without removing them, in the order they would pop. Ties can
+// be scattered anywhere in the heap, so this scans the whole array. An empty
+// queue returns an empty slice.
+func (pq *PriorityQueue[T]) PeekTies() []*Item[T] {
+	ties := []*Item[T]{}
+	top, ok := pq.Peek()
+	if !ok {
+		return ties
+	}
+	less := pq.comparator()
+	for _, item := range pq.items {
+		if !less(top, item) && !less(item, top) {
+			ties = append(ties, item)
+		}
+	}
+	slices.SortFunc(ties, bySeq[T])
+	return ties
+}
+
+// bySeq orders items by insertion, for use with slices.SortFunc.
+func bySeq[T any](a, b *Item[T]) int { return cmp.Compare(a.seq, b.seq) }
+
+// Range returns every item with lo <= priority <= hi, sorted by priority and
+// then insertion order, without modifying the queue. A heap offers no range
+// lookup, so this scans the whole array: O(n) plus sorting the matches. It
+// returns nil if nothing matches.
+func (pq *PriorityQueue[T]) Range(lo, hi int64) []*Item[T] {
+	var found []*Item[T]
+	for _, item := range pq.items {
+		if lo <= item.priority && item.priority <= hi {
+			found = append(found, item)
+		}
+	}
+	slices.SortFunc(found, func(a, b *Item[T]) int {
+		return cmp.Or(cmp.Compare(a.priority, b.priority), bySeq(a, b))
+	})
+	return found
+}
+
+// SortedValues returns the queued values in pop order without modifying the
+// queue. Equal priorities are listed in insertion order, as they would pop.
+func (pq *PriorityQueue[T]) SortedValues() []T {
+	values := make([]T, 0, pq.Len())
+	pq.walk(func(item *Item[T]) bool {
+		values = append(values, item.value)
+		return true
+	})
+	return values
+}
+
+// TopK returns the k items that would pop first, in pop order, without
+// modifying the queue. Only the heap frontier below the items already
+// selected is explored, so it costs O(k log k) however large the queue is,
+// rather than the O(n log n) of draining a copy.
+func (pq *PriorityQueue[T]) TopK(k int) []*Item[T] {
+	return pq.PeekN(k)
+}
+
+// Nth returns the item at pop position n (0 is the top), which is unrelated
+// to its array index. ok is false if n is out of range. Reaching position n
+// walks the heap in order, costing O(n log n); the queue is not modified.
+func (pq *PriorityQueue[T]) Nth(n int) (*Item[T], bool) {
+	if n < 0 || n >= pq.Len() {
+		return nil, false
+	}
+	var nth *Item[T]
+	i := 0
+	pq.walk(func(item *Item[T]) bool {
+		if i == n {
+			nth = item
+			return false
+		}
+		i++
+		return true
+	})
+	return nth, true
+}
+
+// All returns an iterator over the queued items in pop order. Breaking out of
+// the loop stops the iteration early. Each iteration walks a copy of the
+// backing array taken when it starts, which costs O(n), so the loop body may
+// push, pop or remove items: the iteration still yields exactly the items
+// queued when it started. Pop order is only guaranteed while no priorities
+// change, though.
+func (pq *PriorityQueue[T]) All() iter.Seq[*Item[T]] {
+	return func(yield func(*Item[T]) bool) {
+		if pq != nil {
+			pq.walkOver(slices.Clone(pq.items), yield)
+		}
+	}
+}
+
+// ForEach calls fn once for every queued item in array order, which is not
+// pop order, in O(n) with no allocation, for side-effect passes that do not
+// care about order. fn may read the item but must not push, pop, remove or
+// reprioritize anything while ForEach runs; doing so is undefined.
+func (pq *PriorityQueue[T]) ForEach(fn func(*Item[T])) {
+	for _, item := range pq.items {
+		fn(item)
+	}
+}
+
+// Validate checks the heap invariant across the whole array: every item's
+// index must match its slot and no child may order before its parent. It
+// returns an error describing the first violation, or nil.
+func (pq *PriorityQueue[T]) Validate() error {
+	for i, item := range pq.items {
+		if item.index != i {
+			return fmt.Errorf("item %v at slot %d has index %d", item.value, i, item.index)
+		}
+		if parent := (i - 1) / 2; i > 0 && pq.Less(i, parent) {
+			return fmt.Errorf("item %v at slot %d (priority %d) orders before its parent at slot %d (priority %d)",
+				item.value, i, item.priority, parent, pq.items[parent].priority)
+		}
+	}
+	return nil
+}
+
+// VerifyAgainstSorted drains a clone of pq and checks that the items come out
+// in the queue's order, which for the default comparator means
+// non-decreasing priority. It returns an error naming the first out-of-order
+// pair, or nil. pq itself is not modified and no OnRemove hook runs, so it
+// is safe to call on a live queue from integration tests.
+func VerifyAgainstSorted[T any](pq *PriorityQueue[T]) error {
+	drained := pq.Clone().DrainSorted()
+	less := pq.comparator()
+	for i := 1; i < len(drained); i++ {
+		prev, item := drained[i-1], drained[i]
+		if less(item, prev) {
+			return fmt.Errorf("pop %d returned %v (priority %d) after %v (priority %d)",
+				i, item.value, item.priority, prev.value, prev.priority)
+		}
+	}
+	return nil
 }
 
 // get the priority of the heap's top item.
-func (pq *PriorityQueue) peakTopPriority() (int64, error) {
-	if len(*pq) > 0 {
-		return (*pq)[0].priority, nil
-	} else {
-		return -1, fmt.Errorf("PriorityQueue is empty.  No top priority.")
+func (pq *PriorityQueue[T]) peakTopPriority() (int64, error) {
+	if priority, ok := pq.PeekPriority(); ok {
+		return priority, nil
+	}
+	return 0, ErrEmptyQueue
+}
+
+// stringLimit is the number of items String prints before summarising the
+// rest.
+const stringLimit = 10
+
+// String renders the queue in priority order, e.g.
+// [{value=foo pri=100} {value=bar pri=200}]. Only the first stringLimit items
+// are printed, followed by "... (+K more)". A named queue is prefixed with
+// its name and a space. The heap itself is not modified.
+func (pq *PriorityQueue[T]) String() string {
+	var b strings.Builder
+	if pq.name != "" {
+		b.WriteString(pq.name)
+		b.WriteByte(' ')
+	}
+	b.WriteByte('[')
+	n := 0
+	pq.walk(func(item *Item[T]) bool {
+		if n == stringLimit {
+			return false
+		}
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "{value=%v pri=%d}", item.value, item.priority)
+		n++
+		return true
+	})
+	if rest := pq.Len() - n; rest > 0 {
+		fmt.Fprintf(&b, " ... (+%d more)", rest)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// PopSequence renders the queue's full pop order, one value:priority line
+// per item with each line ending in a newline, for comparing against a
+// golden file. Ties appear in the order the queue would pop them. The queue
+// is not modified, and an empty queue renders as "".
+func (pq *PriorityQueue[T]) PopSequence() string {
+	var b strings.Builder
+	pq.walk(func(item *Item[T]) bool {
+		fmt.Fprintf(&b, "%v:%d\n", item.value, item.priority)
+		return true
+	})
+	return b.String()
+}
+
+// walk calls yield with the queued items in pop order until yield returns
+// false. It leaves the heap and the items' indices untouched: a small
+// auxiliary heap of slot numbers, seeded with the root and fed the children
+// of each visited slot, produces the k-th item in O(log k).
+func (pq *PriorityQueue[T]) walk(yield func(*Item[T]) bool) {
+	if pq != nil {
+		pq.walkOver(pq.items, yield)
+	}
+}
+
+// walkOver is walk over items, a heap ordered like pq.
+func (pq *PriorityQueue[T]) walkOver(items []*Item[T], yield func(*Item[T]) bool) {
+	if len(items) == 0 {
+		return
+	}
+	frontier := &slotHeap[T]{pq: pq, items: items, slots: []int{0}}
+	for len(frontier.slots) > 0 {
+		i := heap.Pop(frontier).(int)
+		if !yield(items[i]) {
+			return
+		}
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(items) {
+				heap.Push(frontier, child)
+			}
+		}
 	}
 }
+
+// slotHeap is a heap of positions in items ordered like pq itself.
+type slotHeap[T any] struct {
+	pq    *PriorityQueue[T]
+	items []*Item[T]
+	slots []int
+}
+
+func (h *slotHeap[T]) Len() int { return len(h.slots) }
+
+func (h *slotHeap[T]) Less(i, j int) bool {
+	return h.pq.before(h.items[h.slots[i]], h.items[h.slots[j]])
+}
+
+func (h *slotHeap[T]) Swap(i, j int) { h.slots[i], h.slots[j] = h.slots[j], h.slots[i] }
+
+func (h *slotHeap[T]) Push(x interface{}) { h.slots = append(h.slots, x.(int)) }
+
+func (h *slotHeap[T]) Pop() interface{} {
+	n := len(h.slots)
+	slot := h.slots[n-1]
+	h.slots = h.slots[:n-1]
+	return slot
+}
@@ -0,0 +1,2108 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// popValues drains pq and returns the values in pop order.
+func popValues[T any](pq *PriorityQueue[T]) []T {
+	var values []T
+	for {
+		item, ok := pq.PopItem()
+		if !ok {
+			return values
+		}
+		values = append(values, item.value)
+	}
+}
+
+// mustValidate fails the test if pq's heap invariant is broken.
+func mustValidate[T any](t *testing.T, pq *PriorityQueue[T]) {
+	t.Helper()
+	if err := pq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateByValueMiddle(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		for i, v := range []string{"a", "b", "c", "d", "e"} {
+			pq.PushValue(v, int64(10*(i+1)))
+		}
+		if !pq.UpdateByValue("c", 5) {
+			t.Fatalf("indexed=%v: UpdateByValue(c) = false", indexed)
+		}
+		if pq.UpdateByValue("missing", 1) {
+			t.Errorf("indexed=%v: UpdateByValue(missing) = true", indexed)
+		}
+		mustValidate(t, pq)
+		if got, want := popValues(pq), []string{"c", "a", "b", "d", "e"}; !slices.Equal(got, want) {
+			t.Errorf("indexed=%v: pop order %v, want %v", indexed, got, want)
+		}
+	}
+}
+
+type event struct {
+	ID   string
+	Tags []string
+}
+
+func TestNonComparablePayload(t *testing.T) {
+	events := []event{{"b", []string{"x"}}, {"a", nil}, {"c", []string{"y", "z"}}}
+	pq := Adapt(events, func(e event) int64 { return int64(e.ID[0]) })
+	mustValidate(t, pq)
+	if item, _ := pq.Peek(); item.value.ID != "a" {
+		t.Fatalf("top %q, want a", item.value.ID)
+	}
+
+	pq = NewPriorityQueue(WithValueIndexBy(func(e event) string { return e.ID }))
+	for i, e := range events {
+		pq.PushValue(e, int64(i))
+	}
+	if !pq.Contains(event{ID: "c"}) {
+		t.Fatal("Contains(c) = false")
+	}
+	if !pq.UpdateByValue(event{ID: "c"}, -1) {
+		t.Fatal("UpdateByValue(c) = false")
+	}
+	clone := pq.Clone()
+	if !clone.Equal(pq) {
+		t.Error("clone differs from original")
+	}
+	if n := pq.RemoveByValues([]event{{ID: "b"}}); n != 1 {
+		t.Errorf("RemoveByValues removed %d, want 1", n)
+	}
+	var ids []string
+	for _, e := range popValues(pq) {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"c", "a"}; !slices.Equal(ids, want) {
+		t.Errorf("pop order %v, want %v", ids, want)
+	}
+}
+
+func TestMinPriorityThenValue(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MinPriorityThenValue[string]))
+	pq.PushValue("b", 5)
+	pq.PushValue("a", 5)
+	if got, want := popValues(pq), []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestThresholdsIgnoreTieBreaks(t *testing.T) {
+	comparators := map[string]func(a, b *Item[string]) bool{
+		"default":     nil,
+		"value":       MinPriorityThenValue[string],
+		"then-longer": ByPriorityThen(func(a, b *Item[string]) bool { return len(a.value) > len(b.value) }),
+	}
+	for name, less := range comparators {
+		build := func() *PriorityQueue[string] {
+			pq := NewPriorityQueue(WithComparator(less))
+			pq.PushValue("x", 100)
+			pq.PushValue("yy", 100)
+			pq.PushValue("z", 101)
+			return pq
+		}
+		if got := len(build().PopExpired(100)); got != 2 {
+			t.Errorf("%s: PopExpired(100) popped %d, want 2", name, got)
+		}
+		if got := len(build().PopReady(100)); got != 2 {
+			t.Errorf("%s: PopReady(100) popped %d, want 2", name, got)
+		}
+		if got := len(build().DrainBatch(10, 100)); got != 2 {
+			t.Errorf("%s: DrainBatch(10, 100) popped %d, want 2", name, got)
+		}
+		if got, _ := build().PurgeExpired(context.Background(), 100, nil); got != 2 {
+			t.Errorf("%s: PurgeExpired(100) purged %d, want 2", name, got)
+		}
+		if due, notDue := build().Split(100); due.Len() != 2 || notDue.Len() != 1 {
+			t.Errorf("%s: Split(100) = %d due, %d not due, want 2 and 1", name, due.Len(), notDue.Len())
+		}
+	}
+}
+
+func TestThresholdsFollowMaxPriority(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MaxPriority[string]))
+	for i, v := range []string{"a", "b", "c", "d"} {
+		pq.PushValue(v, int64(i))
+	}
+	var got []string
+	for _, item := range pq.PopExpired(2) {
+		got = append(got, item.value)
+	}
+	if want := []string{"d", "c"}; !slices.Equal(got, want) {
+		t.Errorf("PopExpired(2) = %v, want %v", got, want)
+	}
+}
+
+func TestCancelableItems(t *testing.T) {
+	calls := map[string]int{}
+	item := func(v string, priority int64) *Item[string] {
+		return NewCancelableItem(v, priority, func() { calls[v]++ })
+	}
+	pq := NewPriorityQueue[string]()
+	popped, removed, updated, expired := item("popped", 1), item("removed", 5), item("updated", 6), item("expired", 2)
+	for _, it := range []*Item[string]{popped, removed, updated, expired} {
+		pq.PushItem(it)
+	}
+	pq.PushItem(NewCancelableItem("nil", 3, nil))
+
+	pq.PopItem()
+	pq.Remove(removed)
+	pq.Update(updated, 7)
+	pq.PopExpired(3)
+	if want := map[string]int{"popped": 1, "removed": 1, "expired": 1}; !maps.Equal(calls, want) {
+		t.Errorf("cancel calls %v, want %v", calls, want)
+	}
+
+	pq.Clear()
+	if calls["updated"] != 1 {
+		t.Errorf("Clear called updated's cancel %d times, want 1", calls["updated"])
+	}
+	pq.PushItem(popped)
+	pq.PopItem()
+	if calls["popped"] != 1 {
+		t.Errorf("cancel ran %d times for a re-pushed item, want 1", calls["popped"])
+	}
+}
+
+func TestAllBreaksEarly(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i, v := range []string{"e", "c", "a", "d", "b"} {
+		pq.PushValue(v, int64([]int{5, 3, 1, 4, 2}[i]))
+	}
+	var got []string
+	for item := range pq.All() {
+		got = append(got, item.value)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("first two items %v, want %v", got, want)
+	}
+	if pq.Len() != 5 {
+		t.Errorf("Len = %d after iterating, want 5", pq.Len())
+	}
+	mustValidate(t, pq)
+}
+
+func TestAllAllowsRemoval(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 20 {
+		pq.PushValue(i, int64(20-i))
+	}
+	n := 0
+	for item := range pq.All() {
+		if !pq.Remove(item) {
+			t.Fatalf("Remove(%d) = false", item.value)
+		}
+		n++
+	}
+	if n != 20 || pq.Len() != 0 {
+		t.Errorf("visited %d items, %d left; want 20 and 0", n, pq.Len())
+	}
+}
+
+func TestDuplicateValuesTracked(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		first := pq.PushValue("x", 20).Item()
+		second := pq.PushValue("x", 10).Item()
+		pq.PushValue("y", 15)
+		if got := pq.GetAllByValue("x"); !slices.Equal(got, []*Item[string]{first, second}) {
+			t.Fatalf("indexed=%v: GetAllByValue(x) = %v, want both in push order", indexed, got)
+		}
+		if item, _ := pq.GetByValueAt("x", 1); item != second {
+			t.Errorf("indexed=%v: GetByValueAt(x, 1) is not the second push", indexed)
+		}
+		pq.UpdateByValue("x", 30)
+		if first.Priority() != 30 || second.Priority() != 10 {
+			t.Errorf("indexed=%v: UpdateByValue touched priorities %d and %d, want 30 and 10",
+				indexed, first.Priority(), second.Priority())
+		}
+		if !pq.UpdateByValueAt("x", 1, 40) || pq.UpdateByValueAt("x", 2, 1) {
+			t.Errorf("indexed=%v: UpdateByValueAt results wrong", indexed)
+		}
+		mustValidate(t, pq)
+	}
+}
+
+func TestMergeIndexesInPushOrder(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[string](true))
+	other := NewPriorityQueue[string]()
+	var pushed []*Item[string]
+	// Falling priorities make the heap array the reverse of push order.
+	for i := range 8 {
+		pushed = append(pushed, other.PushValue("x", int64(100-i)).Item())
+	}
+	pq.Merge(other)
+	if got := pq.GetAllByValue("x"); !slices.Equal(got, pushed) {
+		t.Error("merged duplicates are not indexed in push order")
+	}
+	if item, _ := pq.GetByValue("x"); item != pushed[0] {
+		t.Error("GetByValue after Merge is not the earliest push")
+	}
+}
+
+func TestDrainToChannelCancel(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[string](true))
+	for i, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		pq.PushValue(v, int64(i))
+	}
+	pq.PushValue("c", 2) // a duplicate that must stay behind the first "c"
+	ch := make(chan *Item[string], 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- pq.DrainToChannel(ctx, ch) }()
+
+	var got []string
+	for _, want := range []string{"a", "b"} {
+		item := <-ch
+		if item.value != want {
+			t.Fatalf("received %q, want %q", item.value, want)
+		}
+		got = append(got, item.value)
+	}
+	// By now "c" sits in the channel buffer and the drain is blocked on the
+	// second "c"; cancel and discard the buffered item unconsumed.
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("DrainToChannel() = %v, want context.Canceled", err)
+	}
+	close(ch)
+	for item := range ch {
+		got = append(got, item.value)
+	}
+	mustValidate(t, pq)
+	if n := pq.Len() + len(got); n != 7 {
+		t.Fatalf("queued %d + sent %d = %d items, want 7", pq.Len(), len(got), n)
+	}
+	if items := pq.lookup("c"); len(items) == 0 || items[0].index < 0 {
+		t.Fatalf("put-back item is not indexed: %v", items)
+	}
+	// The queue holds exactly what was never sent, still in order.
+	want := []string{"a", "b", "c", "c", "d", "e", "f"}[len(got):]
+	if rest := popValues(pq); !slices.Equal(rest, want) {
+		t.Errorf("queue retained %v, want %v", rest, want)
+	}
+}
+
+func TestDrainToChannelRace(t *testing.T) {
+	var removed int
+	pq := NewPriorityQueue(WithOnRemove(func(*Item[int]) { removed++ }))
+	for i := range 200 {
+		pq.PushValue(i, int64(i%17))
+	}
+	ch := make(chan *Item[int])
+	done := make(chan error, 1)
+	go func() {
+		done <- pq.DrainToChannel(context.Background(), ch)
+		close(ch)
+	}()
+	n := 0
+	for item := range ch {
+		// The receiver owns what it gets and may change it straight away.
+		item.SetPriority(-1)
+		item.SetMeta("seen", "yes")
+		n++
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n != 200 || removed != 200 || pq.Len() != 0 {
+		t.Errorf("sent %d, removed %d, left %d; want 200, 200, 0", n, removed, pq.Len())
+	}
+}
+
+func TestPopResetsIndex(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	pq.PushValue(1, 1)
+	pq.PushValue(2, 2)
+	item, _ := pq.PopItem()
+	if item.index != -1 {
+		t.Errorf("popped item index = %d, want -1", item.index)
+	}
+}
+
+func TestPushAll(t *testing.T) {
+	// One batch below the crossover and one above it.
+	for _, k := range []int{10, 5000} {
+		pq := NewPriorityQueue(WithValueIndex[int](true))
+		for i := range 1000 {
+			pq.PushValue(i, int64(i*37%1000))
+		}
+		items := make([]*Item[int], k)
+		for i := range items {
+			items[i] = NewItem(1000+i, int64(i*53%k))
+		}
+		if n := pq.PushAll(items); n != k {
+			t.Fatalf("k=%d: PushAll() = %d", k, n)
+		}
+		mustValidate(t, pq)
+		if !pq.Contains(1000) || pq.Len() != 1000+k {
+			t.Fatalf("k=%d: Len() = %d, Contains(1000) = %v", k, pq.Len(), pq.Contains(1000))
+		}
+	}
+}
+
+func TestPushAllSkipsQueuedAndNil(t *testing.T) {
+	// One batch below the crossover and one above it.
+	for _, k := range []int{4, 200} {
+		pq := NewPriorityQueue(WithMaxSize[int](50))
+		queued := make([]*Item[int], 20)
+		for i := range queued {
+			queued[i] = pq.PushValue(i, int64(i)).Item()
+		}
+		fresh := NewItem(-1, -1)
+		batch := []*Item[int]{queued[3], nil, fresh, fresh, queued[0]}
+		for i := range k {
+			batch = append(batch, NewItem(100+i, int64(i)))
+		}
+		want := min(1+k, 30)
+		if n := pq.PushAll(batch); n != want {
+			t.Errorf("k=%d: PushAll() = %d, want %d", k, n, want)
+		}
+		if pq.Len() != 20+want {
+			t.Errorf("k=%d: Len() = %d, want %d", k, pq.Len(), 20+want)
+		}
+		mustValidate(t, pq)
+		if top, _ := pq.Peek(); top != fresh {
+			t.Errorf("k=%d: Peek() = %v, want the fresh item", k, top.value)
+		}
+	}
+}
+
+// BenchmarkPushAll pushes a batch of k items into a queue of n with each
+// strategy; PushAll should track the faster of the two at every ratio.
+func BenchmarkPushAll(b *testing.B) {
+	const n = 1 << 12
+	strategies := []struct {
+		name string
+		push func(*PriorityQueue[int], []*Item[int])
+	}{
+		{"each", func(pq *PriorityQueue[int], items []*Item[int]) { pq.pushEach(items, len(items)) }},
+		{"heapify", func(pq *PriorityQueue[int], items []*Item[int]) { pq.pushHeapify(items, len(items)) }},
+		{"PushAll", func(pq *PriorityQueue[int], items []*Item[int]) { pq.PushAll(items) }},
+	}
+	for _, k := range []int{n / 16, n / 4, n, 2 * n, 4 * n, 16 * n} {
+		for _, s := range strategies {
+			b.Run(fmt.Sprintf("k=%.4gn/%s", float64(k)/n, s.name), func(b *testing.B) {
+				base := make([]*Item[int], n)
+				for i := range base {
+					base[i] = NewItem(i, int64(i*7919%n))
+				}
+				batch := make([]*Item[int], k)
+				for i := range batch {
+					batch[i] = NewItem(i, int64(i*104729%n))
+				}
+				for range b.N {
+					b.StopTimer()
+					pq := NewPriorityQueue(WithCapacity[int](n + k))
+					pq.PushAll(base)
+					b.StartTimer()
+					s.push(pq, batch)
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateSamePriority(t *testing.T) {
+	var ops bytes.Buffer
+	pq := NewPriorityQueue(WithOpLog[int](&ops))
+	for i := range 10 {
+		pq.PushValue(i, int64(i%4))
+	}
+	before := slices.Clone(pq.items)
+	ops.Reset()
+	item := pq.items[5]
+	if !pq.Update(item, item.priority) {
+		t.Fatal("Update() = false")
+	}
+	if !slices.Equal(pq.items, before) || item.index != 5 {
+		t.Errorf("no-op Update moved items; item index %d, want 5", item.index)
+	}
+	if ops.Len() != 0 {
+		t.Errorf("no-op Update logged %q", ops.String())
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	pq := NewPriorityQueue[int]()
+	for i := range 1024 {
+		pq.PushValue(i, int64(i*7919%1024))
+	}
+	b.Run("same-priority", func(b *testing.B) {
+		for i := range b.N {
+			item := pq.items[i%1024]
+			pq.Update(item, item.priority)
+		}
+	})
+	b.Run("new-priority", func(b *testing.B) {
+		for i := range b.N {
+			item := pq.items[i%1024]
+			pq.Update(item, item.priority^1)
+		}
+	})
+}
+
+func TestNewPriorityQueueFromItems(t *testing.T) {
+	priorities := []int64{50, 20, 90, 10, 70, 30, 80, 60, 40}
+	items := make([]*Item[int], len(priorities))
+	for i, p := range priorities {
+		items[i] = NewItem(i, p)
+	}
+	pq := NewPriorityQueueFromItems(items...)
+	mustValidate(t, pq)
+	for i, item := range pq.items {
+		if item.index != i {
+			t.Fatalf("slot %d holds index %d", i, item.index)
+		}
+	}
+	var got []int64
+	for pq.Len() > 0 {
+		got = append(got, heap.Pop(pq).(*Item[int]).priority)
+	}
+	if !slices.IsSorted(got) || len(got) != len(priorities) {
+		t.Errorf("pop order %v, want ascending %d priorities", got, len(priorities))
+	}
+}
+
+func TestNewPriorityQueueCapacity(t *testing.T) {
+	pq := NewPriorityQueue(WithCapacity[int](32))
+	if pq.Len() != 0 || pq.Cap() < 32 {
+		t.Errorf("Len() = %d, Cap() = %d; want 0, >= 32", pq.Len(), pq.Cap())
+	}
+}
+
+func TestItemAccessors(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	a, b := NewItem("a", 10), NewItem("b", 20)
+	pq.PushItem(a)
+	pq.PushItem(b)
+	if a.Value() != "a" || a.Priority() != 10 || a.Index() != 0 {
+		t.Fatalf("a = %q/%d at %d", a.Value(), a.Priority(), a.Index())
+	}
+	b.SetPriority(5)
+	if !pq.FixByValue("b") {
+		t.Fatal("FixByValue(b) = false")
+	}
+	if top, _ := pq.Peek(); top != b || b.Index() != 0 {
+		t.Errorf("top = %q, b at %d; want b at 0", top.Value(), b.Index())
+	}
+	pq.PopItem()
+	if b.Index() != -1 {
+		t.Errorf("popped item Index() = %d, want -1", b.Index())
+	}
+}
+
+func TestPeek(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if item, ok := pq.Peek(); ok || item != nil {
+		t.Fatalf("Peek() on empty = %v, %v", item, ok)
+	}
+	if _, err := pq.peakTopPriority(); !errors.Is(err, ErrEmptyQueue) {
+		t.Fatalf("peakTopPriority() on empty = %v", err)
+	}
+	pq.PushValue("old", 100)
+	pq.PushValue("older", 50)
+	item, ok := pq.Peek()
+	if !ok || item.value != "older" || pq.Len() != 2 {
+		t.Fatalf("Peek() = %v, %v with Len %d", item, ok, pq.Len())
+	}
+	if p, _ := pq.PeekPriority(); p != 50 {
+		t.Errorf("PeekPriority() = %d, want 50", p)
+	}
+}
+
+func TestRemoveMiddle(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	items := make([]*Item[int], 100)
+	for i := range items {
+		items[i] = NewItem(i, int64(i*37%100))
+		pq.PushItem(items[i])
+	}
+	for _, i := range []int{50, 3, 97, 42, 0} {
+		item := items[i]
+		if !pq.Remove(item) {
+			t.Fatalf("Remove(%d) = false", i)
+		}
+		if item.index != -1 {
+			t.Errorf("removed item index = %d, want -1", item.index)
+		}
+		if pq.Remove(item) {
+			t.Errorf("second Remove(%d) = true", i)
+		}
+		mustValidate(t, pq)
+	}
+	if pq.Len() != 95 {
+		t.Errorf("Len() = %d, want 95", pq.Len())
+	}
+}
+
+func TestGenericPayloads(t *testing.T) {
+	type buffer struct {
+		ID   int
+		Size int
+	}
+	bq := NewPriorityQueue[buffer]()
+	bq.PushValue(buffer{1, 100}, 30)
+	bq.PushValue(buffer{2, 200}, 10)
+	if item, ok := bq.PopItem(); !ok || item.Value() != (buffer{2, 200}) {
+		t.Fatalf("PopItem() = %v, %v", item, ok)
+	}
+
+	var sq *StringPriorityQueue = NewPriorityQueue[string]()
+	var item *StringItem = NewItem("x", 1)
+	sq.PushItem(item)
+	sq.PushValue("y", 0)
+	if got := popValues(sq); !slices.Equal(got, []string{"y", "x"}) {
+		t.Errorf("pop order %v, want [y x]", got)
+	}
+}
+
+func TestMinHeapOrder(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("thirty", 30)
+	pq.PushValue("ten", 10)
+	pq.PushValue("twenty", 20)
+	if item, _ := pq.PopItem(); item.priority != 10 {
+		t.Errorf("first Pop() priority = %d, want 10", item.priority)
+	}
+}
+
+func TestMinAndMaxComparators(t *testing.T) {
+	priorities := []int64{40, 10, 30, 20, 50}
+	minQ := NewPriorityQueue[int]()
+	maxQ := NewPriorityQueue(WithComparator(MaxPriority[int]))
+	for i, p := range priorities {
+		minQ.PushValue(i, p)
+		maxQ.PushValue(i, p)
+	}
+	asc, desc := popValues(minQ), popValues(maxQ)
+	slices.Reverse(desc)
+	if !slices.Equal(asc, desc) {
+		t.Errorf("min order %v is not the reverse of max order %v", asc, desc)
+	}
+	if want := []int{1, 3, 2, 0, 4}; !slices.Equal(asc, want) {
+		t.Errorf("min order %v, want %v", asc, want)
+	}
+}
+
+func TestEqualPrioritiesPopFIFO(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	other := NewPriorityQueue[string]()
+	other.PushValue("unrelated", 100) // sequence numbers are per queue
+	for _, v := range []string{"first", "second", "third"} {
+		pq.PushValue(v, 100)
+	}
+	if got, want := popValues(pq), []string{"first", "second", "third"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestContainsAndGetByValue(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		first := pq.PushValue("a", 10).Item()
+		pq.PushValue("b", 20)
+		if !pq.Contains("a") || pq.Contains("z") {
+			t.Fatalf("indexed=%v: Contains(a), Contains(z) = %v, %v", indexed, pq.Contains("a"), pq.Contains("z"))
+		}
+		// Duplicates are both kept; lookups find the first pushed.
+		second := pq.PushValue("a", 5).Item()
+		if item, ok := pq.GetByValue("a"); !ok || item != first {
+			t.Errorf("indexed=%v: GetByValue(a) = %v, %v; want the first push", indexed, item, ok)
+		}
+		if item, ok := pq.GetByValueAt("a", 1); !ok || item != second {
+			t.Errorf("indexed=%v: GetByValueAt(a, 1) = %v, %v", indexed, item, ok)
+		}
+		pq.Remove(first)
+		if item, _ := pq.GetByValue("a"); item != second {
+			t.Errorf("indexed=%v: after removing the first, GetByValue(a) = %v", indexed, item)
+		}
+		if _, ok := pq.GetByValue("z"); ok {
+			t.Errorf("indexed=%v: GetByValue(z) found an item", indexed)
+		}
+	}
+}
+
+func TestClearAndRefill(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	var old []*Item[int]
+	for i := range 100 {
+		old = append(old, pq.PushValue(i, int64(100-i)).Item())
+	}
+	capBefore := pq.Cap()
+	pq.Clear()
+	if pq.Len() != 0 || pq.Cap() != capBefore {
+		t.Fatalf("after Clear Len() = %d, Cap() = %d; want 0, %d", pq.Len(), pq.Cap(), capBefore)
+	}
+	for _, item := range old {
+		if item.index != -1 {
+			t.Fatalf("cleared item index = %d, want -1", item.index)
+		}
+	}
+	if pq.Contains(5) {
+		t.Fatal("Contains(5) after Clear")
+	}
+	for i := range 10 {
+		pq.PushValue(i, int64(i*7%10))
+	}
+	mustValidate(t, pq)
+	if got, want := popValues(pq), []int{0, 3, 6, 9, 2, 5, 8, 1, 4, 7}; !slices.Equal(got, want) {
+		t.Errorf("refilled pop order %v, want %v", got, want)
+	}
+}
+
+func TestPopExpired(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	if got := pq.PopExpired(100); got != nil {
+		t.Fatalf("PopExpired on empty = %v", got)
+	}
+	for i := range 10 {
+		pq.PushValue(i, int64(i*10))
+	}
+	got := pq.PopExpired(40)
+	if len(got) != 5 || got[0].priority != 0 || got[4].priority != 40 {
+		t.Fatalf("PopExpired(40) returned %d items", len(got))
+	}
+	mustValidate(t, pq)
+	if got := pq.PopExpired(-1); got != nil || pq.Len() != 5 {
+		t.Errorf("PopExpired(-1) = %v with Len %d", got, pq.Len())
+	}
+}
+
+// BenchmarkPopExpired compares one PopExpired call against the equivalent
+// peek-and-pop loop written by a caller.
+func BenchmarkPopExpired(b *testing.B) {
+	const n, threshold = 1024, 512
+	fill := func() *PriorityQueue[int] {
+		pq := NewPriorityQueue(WithCapacity[int](n))
+		for i := range n {
+			pq.PushValue(i, int64(i*7919%n))
+		}
+		return pq
+	}
+	b.Run("PopExpired", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			pq := fill()
+			b.StartTimer()
+			pq.PopExpired(threshold)
+		}
+	})
+	b.Run("caller-loop", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			pq := fill()
+			b.StartTimer()
+			var expired []*Item[int]
+			for {
+				top, ok := pq.Peek()
+				if !ok || top.Priority() > threshold {
+					break
+				}
+				item, _ := pq.PopItem()
+				expired = append(expired, item)
+			}
+		}
+	})
+}
+
+func TestString(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if s := pq.String(); s != "[]" {
+		t.Errorf("empty String() = %q", s)
+	}
+	pq.PushValue("bar", 200)
+	pq.PushValue("foo", 100)
+	want := "[{value=foo pri=100} {value=bar pri=200}]"
+	if s := pq.String(); s != want {
+		t.Errorf("String() = %q, want %q", s, want)
+	}
+	for i := range 15 {
+		pq.PushValue("x", int64(300+i))
+	}
+	if s := pq.String(); !strings.HasSuffix(s, "{value=x pri=307} ... (+7 more)]") {
+		t.Errorf("long String() = %q", s)
+	}
+	if pq.Len() != 17 {
+		t.Errorf("String changed Len to %d", pq.Len())
+	}
+}
+
+func TestIsEmptyAndCap(t *testing.T) {
+	pq := NewPriorityQueue(WithCapacity[int](4))
+	if !pq.IsEmpty() || pq.Cap() != 4 {
+		t.Fatalf("new queue IsEmpty() = %v, Cap() = %d", pq.IsEmpty(), pq.Cap())
+	}
+	pq.PushValue(1, 1)
+	if pq.IsEmpty() {
+		t.Error("IsEmpty() = true with one item")
+	}
+	for i := range 10 {
+		pq.PushValue(i, int64(i))
+	}
+	if pq.Cap() < pq.Len() || pq.Cap() <= 4 {
+		t.Errorf("Cap() = %d after growing to %d items", pq.Cap(), pq.Len())
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[string](true))
+	a := pq.PushValue("a", 10).Item()
+	pq.PushValue("b", 20)
+	clone := pq.Clone()
+	pq.Update(a, 30)
+	pq.PushValue("c", 5)
+	if got := clone.PopSequence(); got != "a:10\nb:20\n" {
+		t.Errorf("clone changed with the original:\n%s", got)
+	}
+	if item, _ := clone.GetByValue("a"); item == a || item.priority != 10 {
+		t.Errorf("clone shares item a or lost its priority")
+	}
+	mustValidate(t, pq)
+}
+
+func TestCloneKeepsPriorityRange(t *testing.T) {
+	pq := NewPriorityQueue(WithPriorityRange[string](0, 100))
+	pq.PushValue("a", 50)
+	clone := pq.Clone()
+	clone.PushValue("low", -5)
+	high := clone.PushValue("high", 500).Item()
+	if high.priority != 100 {
+		t.Errorf("clone pushed priority 500 as %d, want 100", high.priority)
+	}
+	if got := clone.PopSequence(); got != "low:0\na:50\nhigh:100\n" {
+		t.Errorf("clone did not clamp:\n%s", got)
+	}
+}
+
+func TestMergeLargeQueues(t *testing.T) {
+	a := NewPriorityQueue(WithValueIndex[int](true))
+	b := NewPriorityQueue(WithValueIndex[int](true))
+	for i := range 500 {
+		a.PushValue(i, int64(i*7919%1000))
+		b.PushValue(500+i, int64(i*104729%1000))
+	}
+	a.Merge(b)
+	mustValidate(t, a)
+	if a.Len() != 1000 || b.Len() != 0 {
+		t.Fatalf("Len() = %d, %d after Merge; want 1000, 0", a.Len(), b.Len())
+	}
+	for i, item := range a.items {
+		if item.index != i {
+			t.Fatalf("slot %d holds index %d", i, item.index)
+		}
+	}
+	seen := make(map[int]bool)
+	for _, v := range popValues(a) {
+		seen[v] = true
+	}
+	if len(seen) != 1000 {
+		t.Errorf("merged queue held %d distinct values, want 1000", len(seen))
+	}
+}
+
+func TestMergeRespectsMaxSize(t *testing.T) {
+	pq := NewPriorityQueue(WithMaxSize[int](3), WithValueIndex[int](true))
+	pq.PushValue(1, 10)
+	other := NewPriorityQueue(WithValueIndex[int](true))
+	for i, p := range []int64{40, 5, 30, 20} {
+		other.PushValue(100+i, p)
+	}
+	pq.Merge(other)
+	mustValidate(t, pq)
+	mustValidate(t, other)
+	if other.Contains(101) || !pq.Contains(101) {
+		t.Error("moved value 101 is indexed by the wrong queue")
+	}
+	if got := popValues(pq); !slices.Equal(got, []int{101, 1, 103}) {
+		t.Errorf("bounded Merge kept %v, want [101 1 103]", got)
+	}
+	if got := popValues(other); !slices.Equal(got, []int{102, 100}) {
+		t.Errorf("other after bounded Merge holds %v, want [102 100]", got)
+	}
+
+	full := NewPriorityQueue(WithHardCapacity[int](1))
+	full.PushValue(1, 1)
+	rest := NewPriorityQueue[int]()
+	rest.PushValue(2, 0)
+	full.Merge(rest)
+	if full.Len() != 1 || rest.Len() != 1 {
+		t.Errorf("Merge into a full queue: Len() = %d, %d; want 1, 1", full.Len(), rest.Len())
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i, v := range []string{"d", "a", "e", "c", "b"} {
+		pq.PushValue(v, int64([]int{4, 1, 5, 3, 2}[i]))
+	}
+	top := pq.PeekN(3)
+	var got []int64
+	for _, item := range top {
+		got = append(got, item.priority)
+	}
+	if !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Errorf("PeekN(3) priorities %v, want [1 2 3]", got)
+	}
+	if pq.Len() != 5 {
+		t.Errorf("Len() = %d after PeekN, want 5", pq.Len())
+	}
+	if n := len(pq.PeekN(10)); n != 5 {
+		t.Errorf("PeekN(10) returned %d items, want 5", n)
+	}
+	mustValidate(t, pq)
+}
+
+func TestErrEmptyQueue(t *testing.T) {
+	_, err := NewPriorityQueue[int]().peakTopPriority()
+	if !errors.Is(fmt.Errorf("purge: %w", err), ErrEmptyQueue) {
+		t.Errorf("wrapped %v does not match ErrEmptyQueue", err)
+	}
+	if err.Error() != "priority queue is empty" {
+		t.Errorf("message %q changed", err)
+	}
+}
+
+func TestInitHeapifiesSlice(t *testing.T) {
+	pq := &PriorityQueue[int]{}
+	for i, p := range []int64{9, 4, 7, 1, 8, 2, 6, 3, 5} {
+		pq.items = append(pq.items, NewItem(i, p))
+	}
+	pq.Init()
+	mustValidate(t, pq)
+	var got []int64
+	for pq.Len() > 0 {
+		item, _ := pq.PopItem()
+		got = append(got, item.priority)
+	}
+	if want := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestDrainSorted(t *testing.T) {
+	if got := NewPriorityQueue[int]().DrainSorted(); got == nil || len(got) != 0 {
+		t.Fatalf("DrainSorted on empty = %#v, want empty non-nil", got)
+	}
+	pq := NewPriorityQueue[int]()
+	for i := range 20 {
+		pq.PushValue(i, int64(i*7%20))
+	}
+	items := pq.DrainSorted()
+	if len(items) != 20 || pq.Len() != 0 {
+		t.Fatalf("drained %d items, %d left", len(items), pq.Len())
+	}
+	for i, item := range items {
+		if item.priority != int64(i) {
+			t.Fatalf("item %d has priority %d", i, item.priority)
+		}
+	}
+}
+
+// BenchmarkDrainSorted compares DrainSorted against a caller popping in a
+// loop.
+func BenchmarkDrainSorted(b *testing.B) {
+	const n = 1024
+	fill := func() *PriorityQueue[int] {
+		pq := NewPriorityQueue(WithCapacity[int](n))
+		for i := range n {
+			pq.PushValue(i, int64(i*7919%n))
+		}
+		return pq
+	}
+	b.Run("DrainSorted", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			b.StopTimer()
+			pq := fill()
+			b.StartTimer()
+			pq.DrainSorted()
+		}
+	})
+	b.Run("caller-loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			b.StopTimer()
+			pq := fill()
+			b.StartTimer()
+			var items []*Item[int]
+			for {
+				item, ok := pq.PopItem()
+				if !ok {
+					break
+				}
+				items = append(items, item)
+			}
+		}
+	})
+}
+
+func TestRemoveExpired(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushItem(NewItemWithExpiry("gone1", 5, 100))
+	pq.PushItem(NewItemWithExpiry("live1", 1, 900))
+	pq.PushItem(NewItem("forever", 3))
+	pq.PushItem(NewItemWithExpiry("gone2", 2, 500))
+	pq.PushItem(NewItemWithExpiry("live2", 4, 501))
+	if got := pq.RemoveExpired(50); got != nil {
+		t.Fatalf("RemoveExpired(50) = %v", got)
+	}
+	var gone []string
+	for _, item := range pq.RemoveExpired(500) {
+		gone = append(gone, item.value)
+	}
+	if !slices.Equal(gone, []string{"gone1", "gone2"}) {
+		t.Errorf("removed %v, want [gone1 gone2]", gone)
+	}
+	mustValidate(t, pq)
+	if got := popValues(pq); !slices.Equal(got, []string{"live1", "forever", "live2"}) {
+		t.Errorf("survivors %v", got)
+	}
+}
+
+func TestOnRemoveHook(t *testing.T) {
+	var pq *PriorityQueue[int]
+	calls := 0
+	pq = NewPriorityQueue(WithOnRemove(func(item *Item[int]) {
+		calls++
+		// The hook may use the queue: the heap is already consistent.
+		if err := pq.Validate(); err != nil {
+			t.Error(err)
+		}
+		pq.Contains(item.value)
+	}))
+	items := make([]*Item[int], 6)
+	for i := range items {
+		items[i] = NewItem(i, int64(i))
+		pq.PushItem(items[i])
+	}
+	pq.PopItem()
+	pq.Remove(items[4])
+	pq.PopExpired(2)
+	if calls != 4 {
+		t.Errorf("hook ran %d times after Pop, Remove and PopExpired, want 4", calls)
+	}
+	pq.Update(items[5], -1)
+	if calls != 4 {
+		t.Errorf("Update ran the hook")
+	}
+}
+
+func TestRemoveWhereEvenPriorities(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	for i := range 20 {
+		pq.PushValue(i, int64(i*7%20))
+	}
+	removed := pq.RemoveWhere(func(item *Item[int]) bool { return item.priority%2 == 0 })
+	if len(removed) != 10 || pq.Len() != 10 {
+		t.Fatalf("removed %d, %d left; want 10 and 10", len(removed), pq.Len())
+	}
+	for _, item := range removed {
+		if item.priority%2 != 0 || item.index != -1 || pq.Contains(item.value) {
+			t.Fatalf("removed item %v (priority %d) wrongly", item.value, item.priority)
+		}
+	}
+	mustValidate(t, pq)
+	for pq.Len() > 0 {
+		if item, _ := pq.PopItem(); item.priority%2 == 0 {
+			t.Fatalf("even priority %d survived", item.priority)
+		}
+	}
+	if got := pq.RemoveWhere(func(*Item[int]) bool { return true }); got != nil {
+		t.Errorf("RemoveWhere on empty = %v", got)
+	}
+}
+
+func TestShrinkToFit(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10000 {
+		pq.PushValue(i, int64(i*7919%10000))
+	}
+	for pq.Len() > 10 {
+		pq.PopItem()
+	}
+	before := pq.Cap()
+	pq.ShrinkToFit()
+	if pq.Cap() >= before/100 {
+		t.Errorf("Cap() = %d after ShrinkToFit, was %d", pq.Cap(), before)
+	}
+	mustValidate(t, pq)
+	fitted := pq.Cap()
+	pq.ShrinkToFit()
+	if pq.Cap() != fitted {
+		t.Errorf("second ShrinkToFit changed Cap from %d to %d", fitted, pq.Cap())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10 {
+		pq.PushValue(i, int64(i))
+	}
+	mustValidate(t, pq)
+	bad := pq.Clone()
+	bad.items[0], bad.items[9] = bad.items[9], bad.items[0]
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() = nil after swapping two items without fixing")
+	}
+	mustValidate(t, pq)
+}
+
+func TestUpdateBatch(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	items := make([]*Item[int], 50)
+	for i := range items {
+		items[i] = NewItem(i, int64(i))
+		pq.PushItem(items[i])
+	}
+	stranger := NewItem(-1, 0)
+	changes := map[*Item[int]]int64{stranger: -100}
+	for i := 0; i < 50; i += 2 {
+		changes[items[i]] = int64(100 - i)
+	}
+	pq.UpdateBatch(changes)
+	mustValidate(t, pq)
+	if stranger.priority != 0 || pq.Len() != 50 {
+		t.Error("UpdateBatch touched an item outside the queue")
+	}
+	if top, _ := pq.Peek(); top != items[1] {
+		t.Errorf("top is %d, want 1", top.value)
+	}
+}
+
+// BenchmarkUpdateBatch compares UpdateBatch against a loop of Update for
+// k = n/2 changed items.
+func BenchmarkUpdateBatch(b *testing.B) {
+	const n = 1 << 12
+	pq := NewPriorityQueue[int]()
+	items := make([]*Item[int], n)
+	for i := range items {
+		items[i] = NewItem(i, int64(i*7919%n))
+		pq.PushItem(items[i])
+	}
+	changes := make(map[*Item[int]]int64, n/2)
+	b.Run("UpdateBatch", func(b *testing.B) {
+		for i := range b.N {
+			clear(changes)
+			for j := range n / 2 {
+				changes[items[(j*2+i)%n]] = int64((j*104729 + i) % n)
+			}
+			pq.UpdateBatch(changes)
+		}
+	})
+	b.Run("Update-loop", func(b *testing.B) {
+		for i := range b.N {
+			clear(changes)
+			for j := range n / 2 {
+				changes[items[(j*2+i)%n]] = int64((j*104729 + i) % n)
+			}
+			for item, p := range changes {
+				pq.Update(item, p)
+			}
+		}
+	})
+}
+
+func TestReplaceTop(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[string](true))
+	if old := pq.ReplaceTop(NewItem("first", 5)); old != nil || pq.Len() != 1 {
+		t.Fatalf("ReplaceTop on empty = %v with Len %d", old, pq.Len())
+	}
+	pq.PushValue("b", 10)
+	pq.PushValue("c", 20)
+	old := pq.ReplaceTop(NewItem("d", 15))
+	if old.value != "first" || old.index != -1 || pq.Contains("first") {
+		t.Fatalf("ReplaceTop returned %v at %d", old.value, old.index)
+	}
+	mustValidate(t, pq)
+	if got := popValues(pq); !slices.Equal(got, []string{"b", "d", "c"}) {
+		t.Errorf("pop order %v, want [b d c]", got)
+	}
+}
+
+func TestReplaceTopRejectsQueuedAndNil(t *testing.T) {
+	pq := NewPriorityQueue(WithPriorityRange[string](0, 100))
+	a := pq.PushValue("a", 10).Item()
+	b := pq.PushValue("b", 20).Item()
+	before := pq.PopSequence()
+	for name, item := range map[string]*Item[string]{"top": a, "queued": b, "nil": nil} {
+		if old := pq.ReplaceTop(item); old != nil {
+			t.Errorf("ReplaceTop(%s) = %v, want nil", name, old.value)
+		}
+		if got := pq.PopSequence(); got != before {
+			t.Errorf("ReplaceTop(%s) changed the queue:\n%s", name, got)
+		}
+		mustValidate(t, pq)
+	}
+	if old := pq.ReplaceTop(NewItem("high", 500)); old != a {
+		t.Fatalf("ReplaceTop returned %v, want a", old)
+	}
+	if got := pq.PopSequence(); got != "b:20\nhigh:100\n" {
+		t.Errorf("ReplaceTop did not clamp:\n%s", got)
+	}
+
+	jittered := NewPriorityQueue(WithJitter[int](1000, 1))
+	jittered.PushValue(0, 0)
+	item := NewItem(1, 0)
+	jittered.ReplaceTop(item)
+	if item.priority == 0 {
+		t.Error("ReplaceTop skipped the jitter")
+	}
+}
+
+// BenchmarkReplaceTop compares ReplaceTop against a Pop followed by a Push,
+// recycling the popped item at a pseudo-random priority each time.
+func BenchmarkReplaceTop(b *testing.B) {
+	const n = 1024
+	fill := func() (*PriorityQueue[int], *Item[int]) {
+		pq := NewPriorityQueue[int]()
+		for i := range n {
+			pq.PushValue(i, int64(i*7919%n))
+		}
+		return pq, NewItem(-1, 0)
+	}
+	b.Run("ReplaceTop", func(b *testing.B) {
+		pq, spare := fill()
+		for i := range b.N {
+			spare.priority = int64(i * 104729 % n)
+			spare = pq.ReplaceTop(spare)
+		}
+	})
+	b.Run("Pop+Push", func(b *testing.B) {
+		pq, spare := fill()
+		for i := range b.N {
+			spare.priority = int64(i * 104729 % n)
+			top, _ := pq.PopItem()
+			pq.PushItem(spare)
+			spare = top
+		}
+	})
+}
+
+func TestSortedValues(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i, v := range []string{"c", "a", "d", "b"} {
+		pq.PushValue(v, int64([]int{3, 1, 4, 2}[i]))
+	}
+	before := slices.Clone(pq.items)
+	if got := pq.SortedValues(); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("SortedValues() = %v", got)
+	}
+	if !slices.Equal(pq.items, before) {
+		t.Error("SortedValues reordered the heap")
+	}
+	if got := NewPriorityQueue[string]().SortedValues(); len(got) != 0 {
+		t.Errorf("SortedValues() on empty = %v", got)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	a := pq.PushValue(1, 10).Item()
+	pq.PushValue(2, 5)
+	pq.Reserve(1000)
+	if pq.Cap() < 1002 || pq.items[a.index] != a {
+		t.Fatalf("Cap() = %d after Reserve(1000), or item a lost its slot", pq.Cap())
+	}
+	c := pq.Cap()
+	pq.Reserve(-5)
+	if pq.Cap() != c {
+		t.Errorf("Reserve(-5) changed Cap from %d to %d", c, pq.Cap())
+	}
+	mustValidate(t, pq)
+}
+
+// BenchmarkReserve pushes a batch of known size with and without reserving
+// room for it first.
+func BenchmarkReserve(b *testing.B) {
+	const batch = 4096
+	for _, reserve := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reserve=%v", reserve), func(b *testing.B) {
+			items := make([]*Item[int], batch)
+			for i := range items {
+				items[i] = NewItem(i, int64(i))
+			}
+			b.ReportAllocs()
+			for range b.N {
+				pq := NewPriorityQueue[int]()
+				if reserve {
+					pq.Reserve(batch)
+				}
+				for _, item := range items {
+					pq.PushItem(item)
+				}
+			}
+		})
+	}
+}
+
+func TestPushValue(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	h := pq.PushValue("a", 10)
+	pq.PushValue("b", 5)
+	if h.Item().Value() != "a" {
+		t.Fatalf("handle refers to %q", h.Item().Value())
+	}
+	if !pq.UpdateHandle(h, 1) {
+		t.Fatal("UpdateHandle() = false")
+	}
+	if top, _ := pq.Peek(); top != h.Item() {
+		t.Errorf("top = %q after reprioritizing a", top.value)
+	}
+	full := NewPriorityQueue(WithMaxSize[string](1))
+	full.PushValue("x", 1)
+	if h := full.PushValue("y", 2); h.Item() != nil {
+		t.Error("PushValue into a full queue returned a handle")
+	}
+}
+
+func TestPopItem(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if item, ok := pq.PopItem(); ok || item != nil {
+		t.Fatalf("PopItem() on empty = %v, %v", item, ok)
+	}
+	pq.PushValue("b", 2)
+	pq.PushValue("a", 1)
+	if item, ok := pq.PopItem(); !ok || item.value != "a" || pq.Len() != 1 {
+		t.Errorf("PopItem() = %v, %v with Len %d", item, ok, pq.Len())
+	}
+}
+
+func TestPeekTies(t *testing.T) {
+	if ties := NewPriorityQueue[int]().PeekTies(); ties == nil || len(ties) != 0 {
+		t.Fatalf("PeekTies() on empty = %#v", ties)
+	}
+	pq := NewPriorityQueue[int]()
+	// Push the 9 first so the 5s end up scattered through the array.
+	pq.PushValue(0, 9)
+	for i := 1; i <= 3; i++ {
+		pq.PushValue(i, 5)
+	}
+	ties := pq.PeekTies()
+	var got []int
+	for _, item := range ties {
+		got = append(got, item.value)
+	}
+	if !slices.Equal(got, []int{1, 2, 3}) || pq.Len() != 4 {
+		t.Errorf("PeekTies() = %v with Len %d, want [1 2 3]", got, pq.Len())
+	}
+}
+
+func TestTopK(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 1000 {
+		pq.PushValue(i, int64(i*7919%1000))
+	}
+	top := pq.TopK(5)
+	for i, item := range top {
+		if item.priority != int64(i) {
+			t.Fatalf("TopK(5)[%d] has priority %d", i, item.priority)
+		}
+	}
+	if len(top) != 5 || pq.Len() != 1000 {
+		t.Errorf("TopK(5) returned %d items, Len %d", len(top), pq.Len())
+	}
+	if got := pq.TopK(0); len(got) != 0 {
+		t.Errorf("TopK(0) = %v", got)
+	}
+	mustValidate(t, pq)
+}
+
+// BenchmarkTopK compares TopK(10) against cloning, fully draining and
+// slicing a 100000-item queue.
+func BenchmarkTopK(b *testing.B) {
+	const n, k = 100_000, 10
+	pq := NewPriorityQueue(WithCapacity[int](n))
+	for i := range n {
+		pq.PushValue(i, int64(i*7919%n))
+	}
+	b.Run("TopK", func(b *testing.B) {
+		for range b.N {
+			if len(pq.TopK(k)) != k {
+				b.Fatal("short TopK")
+			}
+		}
+	})
+	b.Run("DrainSorted", func(b *testing.B) {
+		for range b.N {
+			if len(pq.Clone().DrainSorted()[:k]) != k {
+				b.Fatal("short drain")
+			}
+		}
+	})
+}
+
+func TestStaleHandle(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	h := pq.PushValue("a", 1)
+	pq.PushValue("b", 2)
+	pq.PopItem()
+	if pq.UpdateHandle(h, 0) {
+		t.Error("UpdateHandle with a popped item's handle = true")
+	}
+	// Re-pushing the item does not revive the old handle.
+	if err := pq.Requeue(h.Item(), 3); err != nil {
+		t.Fatal(err)
+	}
+	if pq.UpdateHandle(h, 0) {
+		t.Error("UpdateHandle with a handle from before Requeue = true")
+	}
+	if pq.UpdateHandle(Handle[string]{}, 0) {
+		t.Error("UpdateHandle with the zero Handle = true")
+	}
+	mustValidate(t, pq)
+}
+
+func TestAdjustSaturates(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	hi := NewItem("hi", math.MaxInt64-1)
+	lo := NewItem("lo", math.MinInt64+1)
+	pq.PushItem(hi)
+	pq.PushItem(lo)
+	pq.Adjust(hi, 10)
+	pq.Adjust(lo, -10)
+	if hi.priority != math.MaxInt64 || lo.priority != math.MinInt64 {
+		t.Errorf("priorities %d, %d; want saturation at the int64 bounds", hi.priority, lo.priority)
+	}
+	pq.Adjust(lo, math.MaxInt64)
+	if lo.priority != -1 {
+		t.Errorf("MinInt64 + MaxInt64 = %d, want -1", lo.priority)
+	}
+	mustValidate(t, pq)
+	if pq.Adjust(NewItem("stranger", 0), 1) {
+		t.Error("Adjust of an unqueued item = true")
+	}
+}
+
+func TestSetComparatorReverses(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10 {
+		pq.PushValue(i, int64(i*3%10))
+	}
+	pq.SetComparator(MaxPriority[int])
+	mustValidate(t, pq)
+	var got []int64
+	for pq.Len() > 0 {
+		item, _ := pq.PopItem()
+		got = append(got, item.priority)
+	}
+	if want := []int64{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}; !slices.Equal(got, want) {
+		t.Errorf("pop order after flipping the comparator %v, want %v", got, want)
+	}
+}
+
+func TestPushOrUpdate(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		first := pq.PushOrUpdate("a", 100)
+		again := pq.PushOrUpdate("a", 50)
+		if again != first || pq.Len() != 1 || first.priority != 50 {
+			t.Errorf("indexed=%v: Len() = %d, priority %d, same item %v", indexed, pq.Len(), first.priority, again == first)
+		}
+	}
+}
+
+func TestFromSlicesRoundTrip(t *testing.T) {
+	values := []string{"c", "a", "b", "d"}
+	priorities := []int64{30, 10, 20, 40}
+	pq, err := FromSlices(values, priorities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, pq)
+	gotValues, gotPriorities := pq.ToSlices()
+	if !slices.Equal(gotValues, []string{"a", "b", "c", "d"}) ||
+		!slices.Equal(gotPriorities, []int64{10, 20, 30, 40}) {
+		t.Errorf("ToSlices() = %v, %v", gotValues, gotPriorities)
+	}
+	if pq.Len() != 4 {
+		t.Errorf("ToSlices changed Len to %d", pq.Len())
+	}
+	if _, err := FromSlices([]string{"a"}, nil); err == nil {
+		t.Error("FromSlices with mismatched lengths returned no error")
+	}
+}
+
+func TestUpdatePoppedItemRejected(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 5 {
+		pq.PushValue(i, int64(i))
+	}
+	popped, _ := pq.PopItem()
+	before := slices.Clone(pq.items)
+	if pq.Update(popped, -1) {
+		t.Error("Update of a popped item = true")
+	}
+	if pq.Update(nil, -1) || pq.Remove(nil) {
+		t.Error("Update(nil) or Remove(nil) = true")
+	}
+	// A foreign item whose stale index is in range must not be mistaken for
+	// the item in that slot.
+	foreign := NewItem(99, 0)
+	foreign.index = 2
+	if pq.Update(foreign, -1) || pq.Remove(foreign) {
+		t.Error("a foreign item with an in-range index was accepted")
+	}
+	if !slices.Equal(pq.items, before) {
+		t.Error("rejected updates changed the heap")
+	}
+	mustValidate(t, pq)
+}
+
+func TestHeight(t *testing.T) {
+	for _, tc := range []struct{ n, height int }{{0, 0}, {1, 1}, {2, 2}, {3, 2}, {7, 3}, {8, 4}} {
+		pq := NewPriorityQueue[int]()
+		for i := range tc.n {
+			pq.PushValue(i, int64(i))
+		}
+		if got := pq.Height(); got != tc.height {
+			t.Errorf("Height() with %d items = %d, want %d", tc.n, got, tc.height)
+		}
+	}
+}
+
+func TestFixByValue(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		pq.PushValue("a", 1)
+		b := pq.PushValue("b", 2).Item()
+		pq.PushValue("c", 3)
+		b.SetPriority(0)
+		if !pq.FixByValue("b") {
+			t.Fatalf("indexed=%v: FixByValue(b) = false", indexed)
+		}
+		if pq.FixByValue("missing") {
+			t.Errorf("indexed=%v: FixByValue(missing) = true", indexed)
+		}
+		if got := popValues(pq); !slices.Equal(got, []string{"b", "a", "c"}) {
+			t.Errorf("indexed=%v: pop order %v, want [b a c]", indexed, got)
+		}
+	}
+}
+
+func TestFixByValueDuplicates(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		pq := NewPriorityQueue(WithValueIndex[string](indexed))
+		pq.PushValue("x", 40)
+		moved := pq.PushValue("x", 20).Item()
+		pq.PushValue("c", 25)
+		moved.SetPriority(78)
+		if !pq.FixByValue("x") {
+			t.Fatalf("indexed=%v: FixByValue(x) = false", indexed)
+		}
+		mustValidate(t, pq)
+		if got, want := pq.PopSequence(), "c:25\nx:40\nx:78\n"; got != want {
+			t.Errorf("indexed=%v: pop order\n%s\nwant\n%s", indexed, got, want)
+		}
+		if lo, _ := pq.Min(); lo.priority != 25 {
+			t.Errorf("indexed=%v: Min() = %d, want 25", indexed, lo.priority)
+		}
+	}
+}
+
+func TestNth(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 30 {
+		pq.PushValue(i, int64(i*11%30))
+	}
+	top, _ := pq.Peek()
+	if first, ok := pq.Nth(0); !ok || first != top {
+		t.Errorf("Nth(0) = %v, Peek() = %v", first, top)
+	}
+	last, ok := pq.Nth(pq.Len() - 1)
+	if _, out := pq.Nth(pq.Len()); !ok || out {
+		t.Fatalf("Nth(Len-1) ok = %v, Nth(Len) ok = %v", ok, out)
+	}
+	drained := pq.Clone().DrainSorted()
+	if last.value != drained[len(drained)-1].value || pq.Len() != 30 {
+		t.Errorf("Nth(Len-1) = %v, want the last drained item", last.value)
+	}
+}
+
+func TestCompactRebuildsIndex(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	items := make([]*Item[int], 10000)
+	for i := range items {
+		items[i] = NewItem(i%5000, int64(i*7919%10000))
+		pq.PushItem(items[i])
+	}
+	for i := 0; i < len(items); i += 3 {
+		pq.Remove(items[i])
+	}
+	pq.Compact()
+	mustValidate(t, pq)
+	want := make(map[int][]*Item[int])
+	for _, item := range slices.SortedFunc(slices.Values(pq.items), bySeq[int]) {
+		want[item.value] = append(want[item.value], item)
+	}
+	got := pq.byValue.(*keyedIndex[int, int]).items
+	if !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("index holds %d keys after Compact, want %d matching the queue", len(got), len(want))
+	}
+}
+
+func TestInitRepairsGarbageIndices(t *testing.T) {
+	pq := &PriorityQueue[int]{}
+	for i, p := range []int64{4, 2, 5, 1, 3} {
+		item := NewItem(i, p)
+		item.index = 1000 - 7*i // garbage
+		pq.items = append(pq.items, item)
+	}
+	pq.Init()
+	mustValidate(t, pq)
+	target := pq.items[3]
+	if !pq.Update(target, 0) {
+		t.Fatal("Update() = false after Init")
+	}
+	if top, _ := pq.Peek(); top != target {
+		t.Errorf("top is %d, want the updated item %d", top.value, target.value)
+	}
+	mustValidate(t, pq)
+}
+
+func TestPurgeExpiredCanceled(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 100 {
+		pq.PushValue(i, int64(i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var purged []int
+	n, err := pq.PurgeExpired(ctx, 50, func(item *Item[int]) {
+		if purged = append(purged, item.value); len(purged) == 3 {
+			cancel()
+		}
+	})
+	if n != 3 || err != context.Canceled {
+		t.Fatalf("PurgeExpired() = %d, %v; want 3, context.Canceled", n, err)
+	}
+	mustValidate(t, pq)
+	if pq.Len() != 97 || !slices.Equal(purged, []int{0, 1, 2}) {
+		t.Errorf("purged %v, %d left", purged, pq.Len())
+	}
+	n, err = pq.PurgeExpired(context.Background(), 50, nil)
+	if n != 48 || err != nil || pq.Len() != 49 {
+		t.Errorf("resumed PurgeExpired() = %d, %v with %d left", n, err, pq.Len())
+	}
+}
+
+func TestPopFairTieRotates(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if _, ok := pq.PopFairTie(); ok {
+		t.Fatal("PopFairTie() on empty = ok")
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		pq.PushValue(v, 7)
+	}
+	pq.PushValue("later", 8)
+	var got []string
+	for i := range 7 {
+		item, _ := pq.PopFairTie()
+		got = append(got, item.value)
+		if err := pq.Requeue(item, item.priority); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			// A newcomer has never been popped, so it goes before a.
+			pq.PushValue("d", 7)
+		}
+	}
+	if want := []string{"a", "b", "c", "d", "a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("PopFairTie order %v, want %v", got, want)
+	}
+	mustValidate(t, pq)
+}
+
+func TestVerifyAgainstSorted(t *testing.T) {
+	broken := false
+	less := func(a, b *Item[int]) bool {
+		if broken {
+			return a.priority > b.priority
+		}
+		return a.priority < b.priority
+	}
+	pq := NewPriorityQueue(WithComparator(less))
+	for i := range 50 {
+		pq.PushValue(i, int64((i*37)%50))
+	}
+	if err := VerifyAgainstSorted(pq); err != nil {
+		t.Fatalf("VerifyAgainstSorted on a healthy queue = %v", err)
+	}
+
+	// Flipping the comparator without re-heapifying leaves a heap built
+	// under one order being drained under the other.
+	broken = true
+	err := VerifyAgainstSorted(pq)
+	broken = false
+	if err == nil {
+		t.Fatal("VerifyAgainstSorted with a corrupted comparator = nil")
+	}
+	if !strings.Contains(err.Error(), "priority") {
+		t.Errorf("error %q does not describe the bad pair", err)
+	}
+
+	if pq.Len() != 50 {
+		t.Fatalf("Len() = %d after verifying, want 50", pq.Len())
+	}
+	mustValidate(t, pq)
+	if err := VerifyAgainstSorted(pq); err != nil {
+		t.Errorf("VerifyAgainstSorted after restoring the comparator = %v", err)
+	}
+}
+
+func TestPopIf(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	called := false
+	if _, ok := pq.PopIf(func(*Item[string]) bool { called = true; return true }); ok || called {
+		t.Fatalf("PopIf on empty = ok %v, pred called %v", ok, called)
+	}
+
+	pq.PushValue("ready", 10)
+	pq.PushValue("later", 20)
+	ready := func(top *Item[string]) bool { return top.priority <= 15 }
+	if item, ok := pq.PopIf(ready); !ok || item.value != "ready" {
+		t.Fatalf("PopIf(ready) = %v, %v, want ready", item, ok)
+	}
+	before := pq.String()
+	if item, ok := pq.PopIf(ready); ok {
+		t.Fatalf("PopIf(ready) popped %v", item.value)
+	}
+	if pq.Len() != 1 || pq.String() != before {
+		t.Errorf("rejected PopIf changed the queue: %s, want %s", pq, before)
+	}
+	mustValidate(t, pq)
+}
+
+func TestDrainBatchResumes(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 20 {
+		pq.PushValue(i, int64(i*7%20))
+	}
+	seen := make(map[int]bool)
+	var last int64 = math.MinInt64
+	for tick := 0; ; tick++ {
+		batch := pq.DrainBatch(3, 14)
+		if batch == nil {
+			break
+		}
+		if len(batch) > 3 {
+			t.Fatalf("tick %d: batch of %d, want at most 3", tick, len(batch))
+		}
+		for _, item := range batch {
+			if seen[item.value] {
+				t.Fatalf("tick %d: %d drained twice", tick, item.value)
+			}
+			if item.priority < last || item.priority > 14 {
+				t.Fatalf("tick %d: drained priority %d after %d", tick, item.priority, last)
+			}
+			seen[item.value] = true
+			last = item.priority
+		}
+	}
+	if len(seen) != 15 || pq.Len() != 5 {
+		t.Fatalf("drained %d items leaving %d, want 15 and 5", len(seen), pq.Len())
+	}
+	if top, _ := pq.PeekPriority(); top != 15 {
+		t.Errorf("top after draining is %d, want 15", top)
+	}
+	if batch := pq.DrainBatch(0, math.MaxInt64); batch != nil {
+		t.Errorf("DrainBatch(0) = %v, want nil", batch)
+	}
+}
+
+func TestNegativeCapacity(t *testing.T) {
+	for name, pq := range map[string]*PriorityQueue[int]{
+		"WithCapacity":     NewPriorityQueue(WithCapacity[int](-5)),
+		"WithHardCapacity": NewPriorityQueue(WithHardCapacity[int](-5)),
+	} {
+		if pq.Len() != 0 || pq.Cap() != 0 {
+			t.Errorf("%s(-5): Len %d, Cap %d, want an empty queue", name, pq.Len(), pq.Cap())
+		}
+		pq.PushValue(1, 1)
+		if pq.Len() != 1 {
+			t.Errorf("%s(-5): push failed", name)
+		}
+	}
+	pq := NewPriorityQueue[int]()
+	pq.Reserve(-5)
+	if pq.Cap() != 0 {
+		t.Errorf("Reserve(-5) left Cap %d, want 0", pq.Cap())
+	}
+	mustValidate(t, pq)
+}
+
+func TestZeroValueNeedsNoInit(t *testing.T) {
+	// Every pushing method keeps the heap ordered, so the zero value pops
+	// correctly without Init.
+	var pq PriorityQueue[string]
+	pq.PushValue("c", 3)
+	heap.Push(&pq, NewItem("a", 1))
+	pq.PushItem(NewItem("d", 4))
+	pq.PushAll([]*Item[string]{NewItem("b", 2), NewItem("e", 5)})
+	if top, ok := pq.Peek(); !ok || top.value != "a" {
+		t.Fatalf("Peek() = %v, %v, want a", top, ok)
+	}
+	if got, want := popValues(&pq), []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestMapPrioritiesOffset(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10 {
+		pq.PushValue(i, int64(i*3%10))
+	}
+	want := pq.Clone().DrainSorted()
+	calls := 0
+	pq.MapPriorities(func(old int64) int64 { calls++; return old + 1000 })
+	if calls != 10 {
+		t.Errorf("fn called %d times, want 10", calls)
+	}
+	mustValidate(t, pq)
+	for i, item := range pq.DrainSorted() {
+		if item.value != want[i].value || item.priority != want[i].priority+1000 {
+			t.Fatalf("pop %d = %d@%d, want %d@%d", i, item.value, item.priority, want[i].value, want[i].priority+1000)
+		}
+	}
+}
+
+func TestMapPrioritiesReorders(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 10 {
+		pq.PushValue(i, int64(i))
+	}
+	// Reverses half the range and folds it onto the other half.
+	pq.MapPriorities(func(old int64) int64 { return (old - 5) * (old - 5) })
+	mustValidate(t, pq)
+	var got []int64
+	for _, item := range pq.DrainSorted() {
+		got = append(got, item.priority)
+	}
+	if want := []int64{0, 1, 1, 4, 4, 9, 9, 16, 16, 25}; !slices.Equal(got, want) {
+		t.Errorf("priorities %v, want %v", got, want)
+	}
+}
+
+func TestDrainFuncStopsOnError(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 8 {
+		pq.PushValue(i, int64(7-i))
+	}
+	errStop := errors.New("stop")
+	var processed []int
+	err := pq.DrainFunc(func(item *Item[int]) error {
+		if len(processed) == 2 {
+			return errStop
+		}
+		processed = append(processed, item.value)
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("DrainFunc = %v, want %v", err, errStop)
+	}
+	if want := []int{7, 6}; !slices.Equal(processed, want) {
+		t.Errorf("processed %v, want %v", processed, want)
+	}
+	mustValidate(t, pq)
+	if got, want := popValues(pq), []int{5, 4, 3, 2, 1, 0}; !slices.Equal(got, want) {
+		t.Errorf("remaining %v, want %v", got, want)
+	}
+
+	pq.PushValue(1, 1)
+	if err := pq.DrainFunc(func(*Item[int]) error { return nil }); err != nil || pq.Len() != 0 {
+		t.Errorf("DrainFunc = %v leaving %d items, want nil and empty", err, pq.Len())
+	}
+}
+
+func TestRangeWindow(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 30 {
+		pq.PushValue(i, int64(i*11%30))
+	}
+	pq.PushValue(100, 12) // a duplicate priority inside the window
+	before := pq.String()
+	var got []int64
+	for _, item := range pq.Range(10, 15) {
+		got = append(got, item.priority)
+	}
+	if want := []int64{10, 11, 12, 12, 13, 14, 15}; !slices.Equal(got, want) {
+		t.Errorf("Range(10, 15) priorities %v, want %v", got, want)
+	}
+	if pq.String() != before {
+		t.Error("Range modified the queue")
+	}
+	if r := pq.Range(40, 50); len(r) != 0 {
+		t.Errorf("Range outside the queue = %d items", len(r))
+	}
+	if r := pq.Range(15, 10); len(r) != 0 {
+		t.Errorf("Range with lo > hi = %d items", len(r))
+	}
+}
+
+func TestAdaptCustomStructs(t *testing.T) {
+	type job struct {
+		name     string
+		deadline int64
+		tags     []string // not comparable, as legacy payloads often are
+	}
+	jobs := []job{
+		{"report", 300, nil},
+		{"backup", 100, []string{"nightly"}},
+		{"email", 200, nil},
+		{"cleanup", 100, nil},
+	}
+	pq := Adapt(jobs, func(j job) int64 { return j.deadline })
+	mustValidate(t, pq)
+	var got []string
+	for _, j := range popValues(pq) {
+		got = append(got, j.name)
+	}
+	if want := []string{"backup", "cleanup", "email", "report"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+	if Adapt[job](nil, nil).Len() != 0 {
+		t.Error("Adapt(nil) is not empty")
+	}
+}
+
+func TestRemoveByValuesBulk(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	items := make([]*Item[int], 5000)
+	for i := range items {
+		items[i] = NewItem(i, int64(i*7919%5000))
+		pq.PushItem(items[i])
+	}
+	var doomed []int
+	for i := 0; i < 5000; i += 5 {
+		doomed = append(doomed, i)
+	}
+	doomed = append(doomed, -1, 10_000, 0) // missing and repeated values
+	if n := pq.RemoveByValues(doomed); n != 1000 {
+		t.Fatalf("RemoveByValues removed %d, want 1000", n)
+	}
+	if pq.Len() != 4000 {
+		t.Fatalf("Len() = %d, want 4000", pq.Len())
+	}
+	mustValidate(t, pq)
+	for i, item := range items {
+		if removed := i%5 == 0; pq.Contains(i) == removed || (item.index == -1) != removed {
+			t.Fatalf("value %d: Contains %v, index %d", i, pq.Contains(i), item.index)
+		}
+	}
+	var last int64 = -1
+	for pq.Len() > 0 {
+		item, _ := pq.PopItem()
+		if item.priority < last || item.value%5 == 0 {
+			t.Fatalf("popped %d@%d after priority %d", item.value, item.priority, last)
+		}
+		last = item.priority
+	}
+}
+
+func TestPopSequenceGolden(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if got := pq.PopSequence(); got != "" {
+		t.Errorf("PopSequence() on empty = %q", got)
+	}
+	pq.PushValue("c", 3)
+	pq.PushValue("tie-first", 1)
+	pq.PushValue("b", 2)
+	pq.PushValue("tie-second", 1)
+	pq.PushValue("neg", -4)
+	before := pq.String()
+	const golden = "neg:-4\ntie-first:1\ntie-second:1\nb:2\nc:3\n"
+	if got := pq.PopSequence(); got != golden {
+		t.Errorf("PopSequence() =\n%s\nwant\n%s", got, golden)
+	}
+	if pq.String() != before || pq.Len() != 5 {
+		t.Error("PopSequence modified the queue")
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	pq := NewPriorityQueue(WithMaxSize[string](3))
+	for _, v := range []string{"a", "b", "c"} {
+		pq.PushValue(v, int64(v[0]))
+	}
+	item, _ := pq.PopItem()
+	if err := pq.Requeue(item, 'b'+1); err != nil {
+		t.Fatal(err)
+	}
+	if item.priority != 'b'+1 || item.index < 0 {
+		t.Errorf("requeued item has priority %d, index %d", item.priority, item.index)
+	}
+	mustValidate(t, pq)
+	// A requeued item counts as newly pushed, so it follows its ties.
+	if got, want := pq.PopSequence(), "b:98\nc:99\na:99\n"; got != want {
+		t.Errorf("after Requeue:\n%s\nwant\n%s", got, want)
+	}
+
+	if err := pq.Requeue(item, 0); !errors.Is(err, ErrItemQueued) {
+		t.Errorf("Requeue of a queued item = %v, want ErrItemQueued", err)
+	}
+	if item.priority != 'b'+1 {
+		t.Errorf("rejected Requeue changed the priority to %d", item.priority)
+	}
+	if err := pq.Requeue(NewItem("d", 1), 1); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Requeue into a full queue = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestSplitPartitions(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	for i := range 40 {
+		pq.PushValue(i, int64(i*13%40))
+	}
+	due, notDue := pq.Split(19)
+	if pq.Len() != 0 || pq.Contains(0) {
+		t.Fatalf("Split left %d items in the original", pq.Len())
+	}
+	if due.Len() != 20 || notDue.Len() != 20 {
+		t.Fatalf("Split sizes %d and %d, want 20 each", due.Len(), notDue.Len())
+	}
+	for name, part := range map[string]*PriorityQueue[int]{"due": due, "notDue": notDue} {
+		mustValidate(t, part)
+		last := int64(math.MinInt64)
+		for part.Len() > 0 {
+			top, _ := part.Peek()
+			if !part.Contains(top.value) {
+				t.Fatalf("%s: value index is missing %d", name, top.value)
+			}
+			item, _ := part.PopItem()
+			if (item.priority <= 19) != (name == "due") {
+				t.Fatalf("%s holds priority %d", name, item.priority)
+			}
+			if item.priority < last {
+				t.Fatalf("%s popped %d after %d", name, item.priority, last)
+			}
+			last = item.priority
+		}
+	}
+}
+
+func TestSplitKeepsNameAndRange(t *testing.T) {
+	pq := NewPriorityQueue(WithName[int]("ingest"), WithPriorityRange[int](0, 100))
+	for i := range 10 {
+		pq.PushValue(i, int64(i*10))
+	}
+	due, notDue := pq.Split(40)
+	for name, part := range map[string]*PriorityQueue[int]{"due": due, "notDue": notDue} {
+		if got := part.Stats().Name; got != "ingest" {
+			t.Errorf("%s: name = %q, want ingest", name, got)
+		}
+		part.PushValue(-1, 500)
+		part.PushValue(-2, -500)
+		mustValidate(t, part)
+		if top, _ := part.Peek(); top.priority != 0 {
+			t.Errorf("%s: out-of-range push has priority %d, want it clamped to 0", name, top.priority)
+		}
+		if last, _ := part.Max(); last.priority != 100 {
+			t.Errorf("%s: out-of-range push has priority %d, want it clamped to 100", name, last.priority)
+		}
+	}
+}
+
+func TestForEachVisitsOnce(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	var want int64
+	for i := range 200 {
+		p := int64(i*31%97 - 40)
+		pq.PushValue(i, p)
+		want += p
+	}
+	var sum int64
+	visits := make(map[*Item[int]]int)
+	pq.ForEach(func(item *Item[int]) {
+		sum += item.priority
+		visits[item]++
+	})
+	if sum != want {
+		t.Errorf("ForEach summed %d, want %d", sum, want)
+	}
+	if len(visits) != 200 {
+		t.Errorf("ForEach visited %d distinct items, want 200", len(visits))
+	}
+	for item, n := range visits {
+		if n != 1 {
+			t.Errorf("item %d visited %d times", item.value, n)
+		}
+	}
+	NewPriorityQueue[int]().ForEach(func(*Item[int]) { t.Error("fn called on an empty queue") })
+}
+
+func TestByPriorityThenValueLength(t *testing.T) {
+	byLength := func(a, b *Item[string]) bool { return len(a.value) > len(b.value) }
+	pq := NewPriorityQueue(WithComparator(ByPriorityThen(byLength)))
+	for _, v := range []string{"mid", "x", "longest", "yy", "also"} {
+		pq.PushValue(v, 5)
+	}
+	pq.PushValue("first-by-priority", 1)
+	pq.PushValue("z", 9)
+	pq.PushValue("w", 5) // ties byLength with x, so push order decides
+	got := popValues(pq)
+	want := []string{"first-by-priority", "longest", "also", "mid", "yy", "x", "w", "z"}
+	if !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestTrimToSmallest(t *testing.T) {
+	pq := NewPriorityQueue(WithValueIndex[int](true))
+	for i := range 100 {
+		pq.PushValue(i, int64(i*37%100))
+	}
+	dropped := pq.TrimToSmallest(10)
+	if len(dropped) != 90 || pq.Len() != 10 {
+		t.Fatalf("TrimToSmallest(10) dropped %d leaving %d", len(dropped), pq.Len())
+	}
+	for _, item := range dropped {
+		if item.priority < 10 || item.index != -1 || pq.Contains(item.value) {
+			t.Fatalf("dropped %d@%d (index %d)", item.value, item.priority, item.index)
+		}
+	}
+	mustValidate(t, pq)
+	for want := range int64(10) {
+		if item, _ := pq.PopItem(); item.priority != want {
+			t.Fatalf("popped priority %d, want %d", item.priority, want)
+		}
+	}
+
+	for i := range 5 {
+		pq.PushValue(i, int64(i))
+	}
+	if got := pq.TrimToSmallest(5); got != nil || pq.Len() != 5 {
+		t.Errorf("TrimToSmallest(Len) = %v, Len %d, want a no-op", got, pq.Len())
+	}
+	if got := pq.TrimToSmallest(0); len(got) != 5 || pq.Len() != 0 {
+		t.Errorf("TrimToSmallest(0) returned %d leaving %d, want 5 and an empty queue", len(got), pq.Len())
+	}
+}
+
+func TestDirectPushNeedsInit(t *testing.T) {
+	var pq PriorityQueue[string]
+	pq.Push(NewItem("z", 9))
+	pq.Push(NewItem("a", 1))
+	if err := pq.Validate(); err == nil {
+		t.Fatal("direct Push kept the heap order; the documented caveat is stale")
+	}
+	pq.Init()
+	mustValidate(t, &pq)
+	if top, _ := pq.PopItem(); top.value != "a" {
+		t.Errorf("PopItem() after Init = %v, want a", top.value)
+	}
+}
@@ -0,0 +1,60 @@
+package priorty_queue
+
+// PriorityQuantile returns the q-quantile of the queued priorities, for q in
+// [0, 1]: the priority at rank floor(q*(Len-1)) in ascending order, so 0 is
+// the minimum, 1 the maximum and 0.5 the lower median. It selects from a copy
+// of the priorities with quickselect, O(n) on average, leaving the heap
+// untouched. ok is false when the queue is empty or q is out of range.
+func (pq *PriorityQueue[T]) PriorityQuantile(q float64) (int64, bool) {
+	if len(pq.items) == 0 || !(q >= 0 && q <= 1) {
+		return 0, false
+	}
+	priorities := make([]int64, len(pq.items))
+	for i, item := range pq.items {
+		priorities[i] = item.priority
+	}
+	return quickselect(priorities, int(q*float64(len(priorities)-1))), true
+}
+
+// quickselect returns the k-th smallest element of a, reordering a.
+func quickselect(a []int64, k int) int64 {
+	lo, hi := 0, len(a)-1
+	for lo < hi {
+		// Median of three guards against sorted input, which a heap's
+		// array often nearly is.
+		mid := lo + (hi-lo)/2
+		if a[mid] < a[lo] {
+			a[mid], a[lo] = a[lo], a[mid]
+		}
+		if a[hi] < a[lo] {
+			a[hi], a[lo] = a[lo], a[hi]
+		}
+		if a[hi] < a[mid] {
+			a[hi], a[mid] = a[mid], a[hi]
+		}
+		pivot := a[mid]
+		i, j := lo, hi
+		for i <= j {
+			for a[i] < pivot {
+				i++
+			}
+			for a[j] > pivot {
+				j--
+			}
+			if i <= j {
+				a[i], a[j] = a[j], a[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return a[k]
+		}
+	}
+	return a[k]
+}
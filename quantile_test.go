@@ -0,0 +1,61 @@
+package priorty_queue
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestPriorityQuantileMedian(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	if _, ok := pq.PriorityQuantile(0.5); ok {
+		t.Fatal("PriorityQuantile on empty = ok")
+	}
+	for i, p := range []int64{50, 10, 40, 20, 30} {
+		pq.PushValue(i, p)
+	}
+	if got, ok := pq.PriorityQuantile(0.5); !ok || got != 30 {
+		t.Errorf("median of 5 = %d, %v, want 30", got, ok)
+	}
+	pq.PushValue(5, 60)
+	before := pq.String()
+	// With an even count the rank floor(0.5*5) = 2 picks the lower median.
+	if got, _ := pq.PriorityQuantile(0.5); got != 30 {
+		t.Errorf("median of 6 = %d, want the lower median 30", got)
+	}
+	if got, _ := pq.PriorityQuantile(0); got != 10 {
+		t.Errorf("0 quantile = %d, want 10", got)
+	}
+	if got, _ := pq.PriorityQuantile(1); got != 60 {
+		t.Errorf("1 quantile = %d, want 60", got)
+	}
+	for _, q := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, ok := pq.PriorityQuantile(q); ok {
+			t.Errorf("PriorityQuantile(%v) = ok", q)
+		}
+	}
+	if pq.String() != before {
+		t.Error("PriorityQuantile modified the queue")
+	}
+	mustValidate(t, pq)
+}
+
+func TestPriorityQuantileMatchesSort(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	pq := NewPriorityQueue[int]()
+	var all []int64
+	for i := range 1001 {
+		// Heavy duplication exercises the partition's equal-pivot handling.
+		p := int64(r.IntN(50))
+		pq.PushValue(i, p)
+		all = append(all, p)
+	}
+	slices.Sort(all)
+	for _, q := range []float64{0, 0.1, 0.25, 0.5, 0.9, 0.99, 1} {
+		want := all[int(q*float64(len(all)-1))]
+		if got, _ := pq.PriorityQuantile(q); got != want {
+			t.Errorf("PriorityQuantile(%v) = %d, want %d", q, got, want)
+		}
+	}
+}
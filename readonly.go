@@ -0,0 +1,31 @@
+package priorty_queue
+
+import "iter"
+
+// ReadOnlyQueue is a view of a PriorityQueue without any mutating methods,
+// for handing a queue to code such as reporting that must not change it. It
+// shares the queue's storage, so it always reflects the queue's current
+// contents. The returned Items belong to the queue and must not be modified.
+type ReadOnlyQueue[T any] struct {
+	pq *PriorityQueue[T]
+}
+
+// ReadOnly returns a read-only view of the queue.
+func (pq *PriorityQueue[T]) ReadOnly() ReadOnlyQueue[T] {
+	return ReadOnlyQueue[T]{pq: pq}
+}
+
+// Len returns the number of items in the queue.
+func (r ReadOnlyQueue[T]) Len() int { return r.pq.Len() }
+
+// Peek returns the queue's top item. ok is false when the queue is empty.
+func (r ReadOnlyQueue[T]) Peek() (*Item[T], bool) { return r.pq.Peek() }
+
+// PeekN returns up to n items in pop order.
+func (r ReadOnlyQueue[T]) PeekN(n int) []*Item[T] { return r.pq.PeekN(n) }
+
+// Contains reports whether an item holding value is queued.
+func (r ReadOnlyQueue[T]) Contains(value T) bool { return r.pq.Contains(value) }
+
+// All returns an iterator over the queued items in pop order.
+func (r ReadOnlyQueue[T]) All() iter.Seq[*Item[T]] { return r.pq.All() }
@@ -0,0 +1,37 @@
+package priorty_queue
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestReadOnlyView(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	view := pq.ReadOnly()
+	if view.Len() != 0 {
+		t.Fatalf("view of an empty queue has Len %d", view.Len())
+	}
+	pq.PushValue("b", 2)
+	pq.PushValue("a", 1)
+	if top, _ := view.Peek(); view.Len() != 2 || top.value != "a" || !view.Contains("b") {
+		t.Errorf("view does not reflect pushes")
+	}
+	pq.PopItem()
+	var seen []string
+	for item := range view.All() {
+		seen = append(seen, item.value)
+	}
+	if !slices.Equal(seen, []string{"b"}) || len(view.PeekN(5)) != 1 {
+		t.Errorf("view after Pop holds %v", seen)
+	}
+
+	typ := reflect.TypeOf(view)
+	for i := range typ.NumMethod() {
+		switch name := typ.Method(i).Name; name {
+		case "All", "Contains", "Len", "Peek", "PeekN":
+		default:
+			t.Errorf("ReadOnlyQueue has unexpected method %s", name)
+		}
+	}
+}
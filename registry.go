@@ -0,0 +1,56 @@
+package priorty_queue
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// NamedQueue is what the registry needs from a queue: its label and its
+// Stats. *PriorityQueue implements it for every value type.
+type NamedQueue interface {
+	Name() string
+	Stats() Stats
+}
+
+// registry holds the queues added with Register. Queues are tracked only
+// while registered, so a service must Unregister a queue it discards or the
+// registry keeps it alive.
+var registry struct {
+	mu     sync.Mutex
+	queues map[NamedQueue]struct{}
+}
+
+// Name returns the queue's WithName label, or "" if it has none.
+func (pq *PriorityQueue[T]) Name() string { return pq.name }
+
+// Register adds q to the package registry listed by RegisteredQueues.
+// Registering a queue twice has no further effect.
+func Register(q NamedQueue) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.queues == nil {
+		registry.queues = make(map[NamedQueue]struct{})
+	}
+	registry.queues[q] = struct{}{}
+}
+
+// Unregister removes q from the registry, if it is there.
+func Unregister(q NamedQueue) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.queues, q)
+}
+
+// RegisteredQueues returns the registered queues sorted by name. Reading
+// their Stats is only safe if nothing modifies the queues concurrently.
+func RegisteredQueues() []NamedQueue {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	queues := make([]NamedQueue, 0, len(registry.queues))
+	for q := range registry.queues {
+		queues = append(queues, q)
+	}
+	slices.SortFunc(queues, func(a, b NamedQueue) int { return cmp.Compare(a.Name(), b.Name()) })
+	return queues
+}
@@ -0,0 +1,44 @@
+package priorty_queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	ingest := NewPriorityQueue(WithName[string]("ingest"))
+	egress := NewPriorityQueue(WithName[int]("egress"))
+	if ingest.Name() != "ingest" || NewPriorityQueue[int]().Name() != "" {
+		t.Fatalf("Name() = %q, want ingest", ingest.Name())
+	}
+	Register(ingest)
+	Register(egress)
+	Register(ingest)
+	t.Cleanup(func() {
+		Unregister(ingest)
+		Unregister(egress)
+	})
+	names := func() []string {
+		var names []string
+		for _, q := range RegisteredQueues() {
+			names = append(names, q.Name())
+		}
+		return names
+	}
+	if got, want := names(), []string{"egress", "ingest"}; !slices.Equal(got, want) {
+		t.Errorf("RegisteredQueues() = %v, want %v", got, want)
+	}
+	Unregister(egress)
+	if got, want := names(), []string{"ingest"}; !slices.Equal(got, want) {
+		t.Errorf("after Unregister: %v, want %v", got, want)
+	}
+
+	ingest.PushValue("a", 1)
+	if s := ingest.String(); !strings.HasPrefix(s, "ingest ") {
+		t.Errorf("String() = %q, want the name first", s)
+	}
+	if st := ingest.Stats(); st.Name != "ingest" {
+		t.Errorf("Stats().Name = %q, want ingest", st.Name)
+	}
+}
@@ -0,0 +1,101 @@
+package priorty_queue
+
+import "sync"
+
+// SafePriorityQueue is a PriorityQueue guarded by a mutex so it can be shared
+// between goroutines. Each method holds the lock for the whole heap
+// operation. The zero value is an empty queue ready to use.
+type SafePriorityQueue[T any] struct {
+	mu sync.Mutex
+	pq PriorityQueue[T]
+}
+
+// NewSafePriorityQueue returns an empty SafePriorityQueue with room for
+// capacity items. A negative capacity is treated as 0.
+func NewSafePriorityQueue[T any](capacity int) *SafePriorityQueue[T] {
+	return &SafePriorityQueue[T]{pq: *NewPriorityQueue(WithCapacity[T](capacity))}
+}
+
+// Push adds item to the queue. Like PushItem, it returns false, changing
+// nothing, if item is nil or already queued.
+func (q *SafePriorityQueue[T]) Push(item *Item[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.PushItem(item)
+}
+
+// Pop removes and returns the top item. ok is false when the queue is empty.
+func (q *SafePriorityQueue[T]) Pop() (*Item[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.PopItem()
+}
+
+// PopIf pops the top item only if pred returns true for it, checking and
+// popping under one lock so no other goroutine can change the top in
+// between. See PriorityQueue.PopIf.
+func (q *SafePriorityQueue[T]) PopIf(pred func(*Item[T]) bool) (*Item[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.PopIf(pred)
+}
+
+// PeekPopReady pops the top item if its priority is at or before nowMillis,
+// checking and popping under a single lock acquisition, for a consumer
+// polling a queue of due times. ok is false, and nothing is popped, when the
+// queue is empty or the top is not yet ready.
+func (q *SafePriorityQueue[T]) PeekPopReady(nowMillis int64) (*Item[T], bool) {
+	return q.PopIf(func(top *Item[T]) bool { return top.priority <= nowMillis })
+}
+
+// Peek returns the top item without removing it. ok is false when the queue
+// is empty.
+func (q *SafePriorityQueue[T]) Peek() (*Item[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Peek()
+}
+
+// Update changes the priority of item and restores the heap ordering. It
+// returns false if item is not currently queued.
+func (q *SafePriorityQueue[T]) Update(item *Item[T], priority int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Update(item, priority)
+}
+
+// Len returns the number of items in the queue.
+func (q *SafePriorityQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Len()
+}
+
+// Remove deletes item from the queue. It returns false if the item is not
+// currently queued.
+func (q *SafePriorityQueue[T]) Remove(item *Item[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Remove(item)
+}
+
+// Stats returns the queue's counters, read under the lock.
+func (q *SafePriorityQueue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.Stats()
+}
+
+// HighWaterMark returns the queue's peak length, read under the lock.
+func (q *SafePriorityQueue[T]) HighWaterMark() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pq.HighWaterMark()
+}
+
+// ResetHighWaterMark restarts peak tracking from the current length.
+func (q *SafePriorityQueue[T]) ResetHighWaterMark() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pq.ResetHighWaterMark()
+}
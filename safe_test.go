@@ -0,0 +1,122 @@
+package priorty_queue
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestSafeConcurrent is meant for go test -race: many pushers and one popper
+// share a queue, and every pushed item must be popped exactly once.
+func TestSafeConcurrent(t *testing.T) {
+	const pushers, perPusher = 8, 500
+	q := NewSafePriorityQueue[int](0)
+	var wg sync.WaitGroup
+	for p := range pushers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perPusher {
+				v := p*perPusher + i
+				item := NewItem(v, int64(v%97))
+				q.Push(item)
+				if i%10 == 0 {
+					q.Update(item, int64(v%13))
+				}
+				q.Peek()
+				q.Len()
+			}
+		}()
+	}
+	seen := make(map[int]bool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for len(seen) < pushers*perPusher {
+			if item, ok := q.Pop(); ok {
+				if seen[item.value] {
+					t.Errorf("popped %d twice", item.value)
+				}
+				seen[item.value] = true
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop() on a drained queue returned an item")
+	}
+}
+
+// TestPeekPopReadyConcurrent is meant for go test -race: producers push due
+// times while one consumer advances its clock and pops whatever is ready.
+// Nothing may be popped before it is due, and nothing may be lost.
+func TestPeekPopReadyConcurrent(t *testing.T) {
+	const producers, perProducer, latest = 4, 500, 2000
+	q := NewSafePriorityQueue[int](0)
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				v := p*perProducer + i
+				q.Push(NewItem(v, int64(v*7919%latest)))
+			}
+		}()
+	}
+	produced := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(produced)
+	}()
+	// The clock stops at the latest due time, where the consumer keeps
+	// polling until the producers are done and nothing more is ready.
+	seen := make(map[int]bool)
+	for now := int64(0); len(seen) < producers*perProducer; now = min(now+1, latest) {
+		finished := false
+		select {
+		case <-produced:
+			finished = true
+		default:
+		}
+		popped := false
+		for {
+			item, ok := q.PeekPopReady(now)
+			if !ok {
+				break
+			}
+			if item.priority > now {
+				t.Fatalf("popped item due at %d at time %d", item.priority, now)
+			}
+			if seen[item.value] {
+				t.Fatalf("popped %d twice", item.value)
+			}
+			seen[item.value] = true
+			popped = true
+		}
+		if finished && now == latest && !popped && len(seen) < producers*perProducer {
+			t.Fatalf("only %d of %d items popped", len(seen), producers*perProducer)
+		}
+	}
+	if item, ok := q.PeekPopReady(1 << 62); ok {
+		t.Errorf("PeekPopReady() on a drained queue = %v", item.value)
+	}
+}
+
+func TestSafePushRejectsDuplicates(t *testing.T) {
+	q := NewSafePriorityQueue[string](0)
+	a, b := NewItem("a", 1), NewItem("b", 2)
+	if !q.Push(a) || !q.Push(b) {
+		t.Fatal("Push of new items = false")
+	}
+	if q.Push(a) {
+		t.Error("second Push of the same item = true")
+	}
+	if q.Push(nil) {
+		t.Error("Push(nil) = true")
+	}
+	if q.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", q.Len())
+	}
+	mustValidate(t, &q.pq)
+}
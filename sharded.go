@@ -0,0 +1,108 @@
+package priorty_queue
+
+// ShardedPriorityQueue spreads items over independently locked min-heap
+// shards, chosen by hashing each item's value, so goroutines pushing or
+// updating different values rarely contend for the same lock.
+//
+// Peek and Pop must find the true minimum across all shards: they lock every
+// shard in turn and compare the tops, so their cost grows linearly with the
+// shard count. Equal priorities in different shards pop in an unspecified
+// order.
+type ShardedPriorityQueue[T any] struct {
+	shards []SafePriorityQueue[T]
+	hash   func(T) uint64
+}
+
+// NewShardedPriorityQueue returns an empty queue with the given number of
+// shards (at least one), assigning values to shards with hash.
+func NewShardedPriorityQueue[T any](shards int, hash func(T) uint64) *ShardedPriorityQueue[T] {
+	return &ShardedPriorityQueue[T]{
+		shards: make([]SafePriorityQueue[T], max(shards, 1)),
+		hash:   hash,
+	}
+}
+
+// shard returns the shard responsible for value.
+func (q *ShardedPriorityQueue[T]) shard(value T) *SafePriorityQueue[T] {
+	return &q.shards[q.hash(value)%uint64(len(q.shards))]
+}
+
+// Push adds item to its value's shard. Like PushItem, it returns false,
+// changing nothing, if item is nil or already queued.
+func (q *ShardedPriorityQueue[T]) Push(item *Item[T]) bool {
+	return item != nil && q.shard(item.value).Push(item)
+}
+
+// Update changes the priority of item. It returns false if item is nil or
+// not queued.
+func (q *ShardedPriorityQueue[T]) Update(item *Item[T], priority int64) bool {
+	return item != nil && q.shard(item.value).Update(item, priority)
+}
+
+// Remove deletes item from the queue. It returns false if item is nil or not
+// queued.
+func (q *ShardedPriorityQueue[T]) Remove(item *Item[T]) bool {
+	return item != nil && q.shard(item.value).Remove(item)
+}
+
+// Peek returns the lowest-priority item across all shards without removing
+// it. ok is false when every shard is empty.
+func (q *ShardedPriorityQueue[T]) Peek() (*Item[T], bool) {
+	q.lockAll()
+	defer q.unlockAll()
+	if s := q.minShard(); s != nil {
+		return s.pq.Peek()
+	}
+	return nil, false
+}
+
+// Pop removes and returns the lowest-priority item across all shards. ok is
+// false when every shard is empty.
+func (q *ShardedPriorityQueue[T]) Pop() (*Item[T], bool) {
+	q.lockAll()
+	defer q.unlockAll()
+	if s := q.minShard(); s != nil {
+		return s.pq.PopItem()
+	}
+	return nil, false
+}
+
+// Len returns the total number of items across all shards.
+func (q *ShardedPriorityQueue[T]) Len() int {
+	n := 0
+	for i := range q.shards {
+		n += q.shards[i].Len()
+	}
+	return n
+}
+
+// minShard returns the shard whose top has the lowest priority, or nil if
+// all are empty. The caller must hold every shard's lock.
+func (q *ShardedPriorityQueue[T]) minShard() *SafePriorityQueue[T] {
+	var best *SafePriorityQueue[T]
+	for i := range q.shards {
+		s := &q.shards[i]
+		top, ok := s.pq.Peek()
+		if !ok {
+			continue
+		}
+		if best == nil || top.priority < best.pq.items[0].priority {
+			best = s
+		}
+	}
+	return best
+}
+
+// lockAll locks every shard, always in index order so concurrent callers
+// cannot deadlock.
+func (q *ShardedPriorityQueue[T]) lockAll() {
+	for i := range q.shards {
+		q.shards[i].mu.Lock()
+	}
+}
+
+func (q *ShardedPriorityQueue[T]) unlockAll() {
+	for i := range q.shards {
+		q.shards[i].mu.Unlock()
+	}
+}
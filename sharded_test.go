@@ -0,0 +1,97 @@
+package priorty_queue
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func hashInt(v int) uint64 { return uint64(v) * 0x9e3779b97f4a7c15 }
+
+// TestShardedConcurrent is meant for go test -race: goroutines push, update
+// and remove concurrently, and the queue must then pop a global minimum each
+// time.
+func TestShardedConcurrent(t *testing.T) {
+	const workers, perWorker = 8, 300
+	q := NewShardedPriorityQueue(4, hashInt)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				v := w*perWorker + i
+				item := NewItem(v, int64(v*7919%1000))
+				q.Push(item)
+				switch i % 3 {
+				case 1:
+					q.Update(item, int64(v%50))
+				case 2:
+					if !q.Remove(item) {
+						t.Errorf("Remove(%d) = false", v)
+					}
+				}
+				q.Peek()
+			}
+		}()
+	}
+	wg.Wait()
+	want := workers * perWorker * 2 / 3
+	if n := q.Len(); n != want {
+		t.Fatalf("Len() = %d, want %d", n, want)
+	}
+	last := int64(-1)
+	for range want {
+		item, ok := q.Pop()
+		if !ok || item.priority < last {
+			t.Fatalf("Pop() = %v, %v after priority %d", item, ok, last)
+		}
+		last = item.priority
+	}
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop() on a drained queue returned an item")
+	}
+}
+
+func TestShardedPushRejectsDuplicates(t *testing.T) {
+	q := NewShardedPriorityQueue(4, hashInt)
+	a := NewItem(7, 1)
+	if !q.Push(a) || !q.Push(NewItem(8, 2)) {
+		t.Fatal("Push of new items = false")
+	}
+	if q.Push(a) {
+		t.Error("second Push of the same item = true")
+	}
+	if q.Push(nil) || q.Update(nil, 1) || q.Remove(nil) {
+		t.Error("nil item accepted")
+	}
+	if q.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", q.Len())
+	}
+	if item, _ := q.Pop(); item != a {
+		t.Errorf("Pop() = %v, want a", item.value)
+	}
+}
+
+// BenchmarkShardedPush compares parallel pushes into the sharded queue
+// against the single-mutex SafePriorityQueue.
+func BenchmarkShardedPush(b *testing.B) {
+	b.Run("safe", func(b *testing.B) {
+		q := NewSafePriorityQueue[int](0)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				q.Push(NewItem(i, int64(i)))
+			}
+		})
+	})
+	for _, shards := range []int{4, 16} {
+		b.Run(fmt.Sprintf("sharded-%d", shards), func(b *testing.B) {
+			q := NewShardedPriorityQueue(shards, hashInt)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					q.Push(NewItem(i, int64(i)))
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package priorty_queue
+
+import "unsafe"
+
+// mapEntryOverhead is a rough per-entry cost of a Go map beyond the key and
+// value themselves (hash bucket slot, tophash and load-factor slack).
+const mapEntryOverhead = 16
+
+// ApproxBytes estimates the memory held by the queue: the queue struct, the
+// backing slice's full capacity, and each item with its value and metadata.
+// Dynamic value sizes are counted for string and []byte values; other types
+// count only their in-line size, so values holding pointers are undercounted.
+// The value index, when enabled, adds a map entry per item. The estimate is
+// meant for budgeting, not accounting: it ignores allocator rounding and map
+// growth slack and typically lands within a few tens of percent of the real
+// figure, while tracking growth linearly.
+func (pq *PriorityQueue[T]) ApproxBytes() int {
+	n := int(unsafe.Sizeof(*pq)) + cap(pq.items)*int(unsafe.Sizeof((*Item[T])(nil)))
+	for _, item := range pq.items {
+		n += approxItemBytes(item)
+	}
+	if pq.byValue != nil {
+		n += len(pq.items) * pq.byValue.entryBytes()
+	}
+	return n
+}
+
+// approxItemBytes estimates the memory held by one item, as for ApproxBytes.
+func approxItemBytes[T any](item *Item[T]) int {
+	n := int(unsafe.Sizeof(*item)) + valueBytes(item.value)
+	if item.ext == nil {
+		return n
+	}
+	n += int(unsafe.Sizeof(*item.ext))
+	for k, v := range item.ext.meta {
+		n += 2*int(unsafe.Sizeof("")) + len(k) + len(v) + mapEntryOverhead
+	}
+	return n
+}
+
+// valueBytes returns the out-of-line bytes referenced by value, for the
+// value types ApproxBytes understands.
+func valueBytes(value any) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return cap(v)
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package priorty_queue
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestApproxBytesGrowsProportionally(t *testing.T) {
+	const n = 1000
+	pq := NewPriorityQueue(WithCapacity[string](3 * n))
+	base := pq.ApproxBytes()
+	fill := func() int {
+		for i := range n {
+			pq.PushValue(strings.Repeat("x", 100), int64(i))
+		}
+		return pq.ApproxBytes()
+	}
+	first := fill() - base
+	second := fill() - base - first
+	if first <= 100*n {
+		t.Fatalf("%d items of 100 bytes grew the estimate by only %d", n, first)
+	}
+	if first != second {
+		t.Errorf("equal batches grew the estimate by %d then %d", first, second)
+	}
+
+	pq.Clear()
+	longer := func() int {
+		for i := range n {
+			pq.PushValue(strings.Repeat("x", 300), int64(i))
+		}
+		return pq.ApproxBytes() - base
+	}()
+	if got, want := longer-first, 200*n; got != want {
+		t.Errorf("tripling value lengths added %d bytes, want %d", got, want)
+	}
+
+	pq.Clear()
+	item := NewItem("v", 0)
+	item.SetMeta("tenant", "acme")
+	pq.PushItem(item)
+	if withMeta, without := pq.ApproxBytes(), base+approxItemBytes(NewItem("v", 0)); withMeta <= without+len("tenant")+len("acme") {
+		t.Errorf("metadata not counted: %d, %d without", withMeta, without)
+	}
+}
+
+func TestApproxBytesTracksAllocation(t *testing.T) {
+	const n = 10_000
+	values := make([]string, n)
+	for i := range values {
+		values[i] = strings.Repeat("y", 64+i%64)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	pq := NewPriorityQueue(WithCapacity[string](n))
+	for i, v := range values {
+		pq.PushItem(NewItem(v, int64(i)))
+	}
+	runtime.ReadMemStats(&after)
+	// Values were allocated beforehand; count them on top of what the queue
+	// allocated itself.
+	allocated := int(after.TotalAlloc-before.TotalAlloc) + func() int {
+		s := 0
+		for _, v := range values {
+			s += len(v)
+		}
+		return s
+	}()
+	est := pq.ApproxBytes()
+	// ApproxBytes documents an estimate within a few tens of percent.
+	if ratio := float64(est) / float64(allocated); ratio < 0.6 || ratio > 1.4 {
+		t.Errorf("ApproxBytes() = %d, allocated %d (ratio %.2f)", est, allocated, ratio)
+	}
+	runtime.KeepAlive(pq)
+}
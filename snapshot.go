@@ -0,0 +1,69 @@
+package priorty_queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// snapshotVersion is the format version written by Snapshot. Restore rejects
+// any other version.
+const snapshotVersion = 1
+
+// Snapshot serializes the queue's values and priorities for crash recovery.
+// The format is a 2-byte big-endian version followed by one record per item
+// in pop order: a 4-byte big-endian length, that many bytes of JSON-encoded
+// value, and an 8-byte big-endian priority. The queue is not modified.
+func (pq *PriorityQueue[T]) Snapshot() ([]byte, error) {
+	buf := binary.BigEndian.AppendUint16(nil, snapshotVersion)
+	var err error
+	pq.walk(func(item *Item[T]) bool {
+		var value []byte
+		if value, err = json.Marshal(item.value); err != nil {
+			return false
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+		buf = append(buf, value...)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(item.priority))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Restore rebuilds a min-heap queue from data written by Snapshot, with ties
+// popping in the same order as in the snapshotted queue. It returns an error
+// for an unknown version or a truncated or malformed buffer.
+func Restore[T any](data []byte) (*PriorityQueue[T], error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("snapshot: missing version header: %w", io.ErrUnexpectedEOF)
+	}
+	if v := binary.BigEndian.Uint16(data); v != snapshotVersion {
+		return nil, fmt.Errorf("snapshot: unsupported version %d", v)
+	}
+	data = data[2:]
+	var items []*Item[T]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("snapshot: record %d: truncated length: %w", len(items), io.ErrUnexpectedEOF)
+		}
+		n := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(len(data)) < uint64(n)+8 {
+			return nil, fmt.Errorf("snapshot: record %d: truncated body: %w", len(items), io.ErrUnexpectedEOF)
+		}
+		var value T
+		if err := json.Unmarshal(data[:n], &value); err != nil {
+			return nil, fmt.Errorf("snapshot: record %d: %w", len(items), err)
+		}
+		priority := int64(binary.BigEndian.Uint64(data[n:]))
+		items = append(items, NewItem(value, priority))
+		data = data[n+8:]
+	}
+	pq := &PriorityQueue[T]{}
+	pq.load(items)
+	return pq, nil
+}
@@ -0,0 +1,83 @@
+package priorty_queue
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for i := range 1000 {
+		// Few distinct priorities, so ties must also come back in order.
+		pq.PushValue(string(rune('a'+i%26))+string(rune('0'+i%10)), int64(i*7919%97))
+	}
+	data, err := pq.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Restore[string](data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 1000 {
+		t.Fatalf("restored Len() = %d, want 1000", restored.Len())
+	}
+	mustValidate(t, restored)
+	want := pq.Clone().DrainSorted()
+	got := restored.DrainSorted()
+	if !slices.EqualFunc(got, want, func(a, b *Item[string]) bool {
+		return a.value == b.value && a.priority == b.priority
+	}) {
+		t.Error("restored queue pops in a different order")
+	}
+}
+
+func TestRestoreCorrupted(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("alpha", 1)
+	pq.PushValue("beta", -2)
+	data, err := pq.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	badVersion := slices.Clone(data)
+	badVersion[1] = 99
+	badJSON := slices.Clone(data)
+	badJSON[6] = '{'
+	tests := []struct {
+		name string
+		data []byte
+		eof  bool
+	}{
+		{"empty", nil, true},
+		{"half header", data[:1], true},
+		{"bad version", badVersion, false},
+		{"truncated length", data[:4], true},
+		{"truncated body", data[:len(data)-3], true},
+		{"bad value", badJSON, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Restore[string](tt.data)
+			if err == nil {
+				t.Fatalf("Restore = %v, want an error", q)
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) != tt.eof {
+				t.Errorf("Restore error %v, want ErrUnexpectedEOF %v", err, tt.eof)
+			}
+		})
+	}
+	// Every prefix must fail cleanly rather than panic, except those ending
+	// on a record boundary: after the header, and after beta's record.
+	boundary := 2 + 4 + len(`"beta"`) + 8
+	for n := range len(data) {
+		if n == 2 || n == boundary {
+			continue
+		}
+		if _, err := Restore[string](data[:n]); err == nil {
+			t.Errorf("Restore of %d-byte prefix = nil error", n)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package priorty_queue
+
+// Stats is a point-in-time summary of a queue for metrics scraping.
+type Stats struct {
+	// Name is the queue's WithName label, if any.
+	Name string
+	// Len is the number of queued items.
+	Len int
+	// TopPriority is the priority of the top item, or 0 when Len is 0.
+	TopPriority int64
+	// Pushes, Pops and Removes count items pushed, popped, and removed by
+	// any other means (Remove, RemoveWhere, Clear, ...) since the queue was
+	// created.
+	Pushes, Pops, Removes uint64
+	// Clamped counts priorities brought into range by WithPriorityRange.
+	Clamped uint64
+}
+
+// Stats returns the queue's current Stats. It is O(1) and does not touch the
+// heap.
+func (pq *PriorityQueue[T]) Stats() Stats {
+	top, _ := pq.PeekPriority()
+	return Stats{
+		Name:        pq.name,
+		Len:         pq.Len(),
+		TopPriority: top,
+		Pushes:      pq.pushes,
+		Pops:        pq.pops,
+		Removes:     pq.removes,
+		Clamped:     pq.clamped,
+	}
+}
+
+// HighWaterMark returns the largest number of items the queue has held since
+// it was created or ResetHighWaterMark was last called.
+func (pq *PriorityQueue[T]) HighWaterMark() int { return pq.maxLen }
+
+// ResetHighWaterMark starts tracking the peak afresh from the current Len.
+func (pq *PriorityQueue[T]) ResetHighWaterMark() { pq.maxLen = len(pq.items) }
@@ -0,0 +1,78 @@
+package priorty_queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStatsCounters(t *testing.T) {
+	pq := NewPriorityQueue(WithName[int]("jobs"))
+	items := make([]*Item[int], 5)
+	for i := range items {
+		items[i] = NewItem(i, int64(10+i))
+		pq.PushItem(items[i])
+	}
+	pq.PopItem()
+	pq.Remove(items[3])
+	pq.RemoveWhere(func(item *Item[int]) bool { return item.value == 4 })
+	want := Stats{Name: "jobs", Len: 2, TopPriority: 11, Pushes: 5, Pops: 1, Removes: 2}
+	if got := pq.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	q := NewSafePriorityQueue[int](0)
+	q.Push(NewItem(1, 1))
+	q.Pop()
+	if got := q.Stats(); got.Pushes != 1 || got.Pops != 1 || got.Len != 0 {
+		t.Errorf("SafePriorityQueue Stats() = %+v", got)
+	}
+}
+
+func TestHighWaterMark(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	for i := range 50 {
+		pq.PushValue(i, int64(i))
+	}
+	for pq.Len() > 5 {
+		pq.PopItem()
+	}
+	for pq.Len() < 30 {
+		pq.PushValue(0, 0)
+	}
+	if got := pq.HighWaterMark(); got != 50 {
+		t.Errorf("HighWaterMark() = %d, want 50", got)
+	}
+	pq.ResetHighWaterMark()
+	if got := pq.HighWaterMark(); got != 30 {
+		t.Errorf("HighWaterMark() after reset = %d, want 30", got)
+	}
+	pq.PushValue(0, 0)
+	if got := pq.HighWaterMark(); got != 31 {
+		t.Errorf("HighWaterMark() after a push = %d, want 31", got)
+	}
+}
+
+func TestSafeHighWaterMarkConcurrent(t *testing.T) {
+	q := NewSafePriorityQueue[int](0)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				q.Push(NewItem(g, int64(i)))
+			}
+		}()
+	}
+	wg.Wait()
+	for range 790 {
+		q.Pop()
+	}
+	if got := q.HighWaterMark(); got != 800 {
+		t.Errorf("HighWaterMark() = %d, want 800", got)
+	}
+	q.ResetHighWaterMark()
+	if got := q.HighWaterMark(); got != 10 {
+		t.Errorf("HighWaterMark() after reset = %d, want 10", got)
+	}
+}
@@ -0,0 +1,117 @@
+package priorty_queue
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+// NewTimedItem returns an Item holding value whose priority is t in
+// milliseconds since epoch.
+func NewTimedItem[T any](value T, t time.Time) *Item[T] {
+	return NewItem(value, t.UnixMilli())
+}
+
+// PriorityTime returns the item's priority as a time, treating it as
+// milliseconds since epoch.
+func (it *Item[T]) PriorityTime() time.Time {
+	return time.UnixMilli(it.priority)
+}
+
+// UpdateTime sets the priority of item to t in milliseconds since epoch and
+// updates the heap accordingly. Like Update, it returns false if item is not
+// in the queue.
+func (pq *PriorityQueue[T]) UpdateTime(item *Item[T], t time.Time) bool {
+	return pq.Update(item, t.UnixMilli())
+}
+
+// AgeMillis returns how long ago, in milliseconds, the item's priority
+// timestamp was as of nowMillis. Future-dated items have age 0, and an age
+// too large for int64 saturates at math.MaxInt64 instead of wrapping.
+func (it *Item[T]) AgeMillis(nowMillis int64) int64 {
+	if it.priority >= nowMillis {
+		return 0
+	}
+	if age := nowMillis - it.priority; age > 0 {
+		return age
+	}
+	return math.MaxInt64
+}
+
+// IsOlderThan reports whether the item's age as of nowMillis exceeds
+// maxAgeMillis, which is the purge predicate for an expiring buffer.
+func (it *Item[T]) IsOlderThan(nowMillis, maxAgeMillis int64) bool {
+	return it.AgeMillis(nowMillis) > maxAgeMillis
+}
+
+// OldestAgeMillis returns the age of the top item as of nowMillis, which for
+// the default min-heap is the longest any queued item has waited. It reads
+// only the top, so it is O(1) and cheap enough to poll for a staleness
+// alarm. ok is false when the queue is empty.
+func (pq *PriorityQueue[T]) OldestAgeMillis(nowMillis int64) (int64, bool) {
+	top, ok := pq.Peek()
+	if !ok {
+		return 0, false
+	}
+	return top.AgeMillis(nowMillis), true
+}
+
+// PopReady pops every item whose priority, read as a fire time in
+// milliseconds since epoch, is at or before nowMillis, and returns them in
+// fire-time order, leaving future items queued. It is the per-tick call for
+// a scheduler built on the default min-heap; it is PopExpired(nowMillis), so
+// under another comparator the threshold follows that order instead.
+func (pq *PriorityQueue[T]) PopReady(nowMillis int64) []*Item[T] {
+	return pq.PopExpired(nowMillis)
+}
+
+// AgedValue is a drained value with its age, as returned by DrainRelative.
+type AgedValue[T any] struct {
+	Value     T
+	AgeMillis int64
+}
+
+// DrainRelative pops every item in pop order, reporting each priority as its
+// age as of nowMillis (see Item.AgeMillis, which clamps future timestamps to
+// 0). The queue is left empty. Draining an empty queue returns an empty,
+// non-nil slice.
+func (pq *PriorityQueue[T]) DrainRelative(nowMillis int64) []AgedValue[T] {
+	aged := make([]AgedValue[T], 0, len(pq.items))
+	for len(pq.items) > 0 {
+		item := heap.Pop(pq).(*Item[T])
+		aged = append(aged, AgedValue[T]{Value: item.value, AgeMillis: item.AgeMillis(nowMillis)})
+	}
+	return aged
+}
+
+// FutureItems returns every item whose priority timestamp is after
+// nowMillis, sorted like Range, to flag bad data such as clock skew. It is an
+// O(n) diagnostic scan, not a heap operation, and returns nil if no item is
+// future-dated.
+func (pq *PriorityQueue[T]) FutureItems(nowMillis int64) []*Item[T] {
+	if nowMillis == math.MaxInt64 {
+		return nil
+	}
+	return pq.Range(nowMillis+1, math.MaxInt64)
+}
+
+// StalledFor returns how long, in milliseconds as of nowMillis, the current
+// top item has been at the head of the queue, or 0 when the queue is empty.
+// A value that keeps growing means nothing is being popped. Each call
+// compares the top item with the one it saw last time, so a change of head is
+// timestamped by the next StalledFor call, and a head that leaves and comes
+// back between two calls counts as never having moved: poll it at the
+// watchdog's interval and treat the result as accurate to that interval.
+// Operations that reorder the heap without changing its top, such as
+// RemoveWhere or UpdateBatch, do not reset it.
+func (pq *PriorityQueue[T]) StalledFor(nowMillis int64) int64 {
+	if len(pq.items) == 0 {
+		pq.head = nil
+		return 0
+	}
+	if pq.items[0] != pq.head {
+		pq.head = pq.items[0]
+		pq.headSince = nowMillis
+	}
+	return max(nowMillis-pq.headSince, 0)
+}
@@ -0,0 +1,164 @@
+package priorty_queue
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimedItem(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC)
+	item := NewTimedItem("x", at)
+	if got, want := item.PriorityTime(), at.Truncate(time.Millisecond); !got.Equal(want) {
+		t.Errorf("PriorityTime() = %v, want %v", got, want)
+	}
+	pq := NewPriorityQueue[string]()
+	pq.PushItem(item)
+	pq.PushItem(NewTimedItem("y", at.Add(time.Second)))
+	if !pq.UpdateTime(item, at.Add(2*time.Second)) {
+		t.Fatal("UpdateTime() = false")
+	}
+	if top, _ := pq.Peek(); top.value != "y" {
+		t.Errorf("top after UpdateTime = %q, want y", top.value)
+	}
+	if got := item.Priority(); got != at.Add(2*time.Second).UnixMilli() {
+		t.Errorf("Priority() = %d", got)
+	}
+}
+
+func TestStalledFor(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if got := pq.StalledFor(1000); got != 0 {
+		t.Errorf("empty queue StalledFor = %d, want 0", got)
+	}
+	pq.PushValue("a", 1)
+	pq.PushValue("b", 2)
+	pq.StalledFor(1000)
+	if got := pq.StalledFor(5000); got != 4000 {
+		t.Errorf("StalledFor after 4s = %d, want 4000", got)
+	}
+	pq.PopItem()
+	if got := pq.StalledFor(6000); got != 0 {
+		t.Errorf("StalledFor after Pop = %d, want 0", got)
+	}
+	if got := pq.StalledFor(6500); got != 500 {
+		t.Errorf("StalledFor = %d, want 500", got)
+	}
+}
+
+func TestStalledForIgnoresReorderingWithSameHead(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("head", 1)
+	c := NewItem("c", 30)
+	pq.PushItem(c)
+	pq.PushValue("d", 40)
+	pq.StalledFor(1000)
+
+	pq.RemoveWhere(func(item *Item[string]) bool { return item.value == "d" })
+	pq.UpdateBatch(map[*Item[string]]int64{c: 20})
+	pq.PushAll([]*Item[string]{NewItem("e", 50), NewItem("f", 60)})
+	pq.MapPriorities(func(p int64) int64 { return p * 2 })
+	if got := pq.StalledFor(5000); got != 4000 {
+		t.Errorf("StalledFor = %d after reordering below the head, want 4000", got)
+	}
+}
+
+func TestAgeMillis(t *testing.T) {
+	future := NewItem("future", 2000)
+	if age := future.AgeMillis(1000); age != 0 {
+		t.Errorf("future-dated item age = %d, want 0", age)
+	}
+	expired := NewItem("expired", 400)
+	if age := expired.AgeMillis(1000); age != 600 {
+		t.Errorf("age = %d, want 600", age)
+	}
+	if !expired.IsOlderThan(1000, 599) || expired.IsOlderThan(1000, 600) {
+		t.Error("IsOlderThan disagrees with AgeMillis")
+	}
+	ancient := NewItem("ancient", math.MinInt64)
+	if age := ancient.AgeMillis(math.MaxInt64); age != math.MaxInt64 {
+		t.Errorf("overflowing age = %d, want math.MaxInt64", age)
+	}
+}
+
+func TestOldestAgeMillis(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	if _, ok := pq.OldestAgeMillis(1000); ok {
+		t.Fatal("OldestAgeMillis on empty = ok")
+	}
+	const now = 50_000
+	for _, ts := range []int64{48_000, 42_500, 49_999, 45_000} {
+		pq.PushValue("event", ts)
+		top, _ := pq.PeekPriority()
+		if age, ok := pq.OldestAgeMillis(now); !ok || age != now-top {
+			t.Errorf("after pushing %d: OldestAgeMillis = %d, %v, want %d", ts, age, ok, now-top)
+		}
+	}
+	pq.PopItem()
+	if age, _ := pq.OldestAgeMillis(now); age != now-45_000 {
+		t.Errorf("after a pop: OldestAgeMillis = %d, want %d", age, now-45_000)
+	}
+}
+
+func TestPopReady(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("t110", 110)
+	pq.PushValue("t90", 90)
+	pq.PushValue("t100", 100)
+	var got []string
+	for _, item := range pq.PopReady(100) {
+		got = append(got, item.value)
+	}
+	if want := []string{"t90", "t100"}; !slices.Equal(got, want) {
+		t.Errorf("PopReady(100) = %v, want %v", got, want)
+	}
+	if top, ok := pq.Peek(); pq.Len() != 1 || !ok || top.value != "t110" {
+		t.Errorf("remaining Len %d top %v, want only t110", pq.Len(), top)
+	}
+	if ready := pq.PopReady(100); len(ready) != 0 {
+		t.Errorf("second PopReady(100) = %d items, want none", len(ready))
+	}
+}
+
+func TestDrainRelative(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("future", 10_500)
+	pq.PushValue("old", 7_000)
+	pq.PushValue("now", 10_000)
+	pq.PushValue("recent", 9_750)
+	got := pq.DrainRelative(10_000)
+	want := []AgedValue[string]{{"old", 3_000}, {"recent", 250}, {"now", 0}, {"future", 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("DrainRelative(10000) = %v, want %v", got, want)
+	}
+	if pq.Len() != 0 {
+		t.Errorf("Len() = %d after DrainRelative, want 0", pq.Len())
+	}
+	if got := pq.DrainRelative(0); got == nil || len(got) != 0 {
+		t.Errorf("DrainRelative on empty = %#v, want an empty non-nil slice", got)
+	}
+}
+
+func TestFutureItems(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	for _, ts := range []int64{900, 1_500, 1_000, 3_000, 200, 1_001} {
+		pq.PushValue("event", ts)
+	}
+	var got []int64
+	for _, item := range pq.FutureItems(1_000) {
+		got = append(got, item.priority)
+	}
+	if want := []int64{1_001, 1_500, 3_000}; !slices.Equal(got, want) {
+		t.Errorf("FutureItems(1000) = %v, want %v", got, want)
+	}
+	if pq.Len() != 6 {
+		t.Errorf("Len() = %d after FutureItems, want 6", pq.Len())
+	}
+	if got := pq.FutureItems(5_000); got != nil {
+		t.Errorf("FutureItems(5000) = %v, want nil", got)
+	}
+	if got := pq.FutureItems(math.MaxInt64); got != nil {
+		t.Errorf("FutureItems(MaxInt64) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,42 @@
+package priorty_queue
+
+// RemoveWithTombstone removes item like Remove but also remembers it in the
+// queue's tombstone ring (see WithTombstones) so Restore can undo the removal
+// until enough later removals push it out. The OnRemove hook still runs at
+// removal time. It returns false if item is not in the queue.
+func (pq *PriorityQueue[T]) RemoveWithTombstone(item *Item[T]) bool {
+	if !pq.Remove(item) {
+		return false
+	}
+	if len(pq.tombstones) > 0 {
+		pq.tombstones[pq.tombstoneNext] = item
+		pq.tombstoneNext = (pq.tombstoneNext + 1) % len(pq.tombstones)
+	}
+	return true
+}
+
+// Restore re-pushes the most recently tombstoned item holding value, with its
+// original priority. Tombstoned items that have since been queued again, for
+// example with Requeue, are dropped from the ring rather than pushed twice.
+// It returns false if no such item is still in the tombstone ring or the
+// queue is full.
+func (pq *PriorityQueue[T]) Restore(value T) bool {
+	n := len(pq.tombstones)
+	for i := 1; i <= n; i++ {
+		slot := (pq.tombstoneNext - i + n) % n
+		item := pq.tombstones[slot]
+		if item == nil || pq.valueKey(item.value) != pq.valueKey(value) {
+			continue
+		}
+		if pq.owns(item) {
+			pq.tombstones[slot] = nil
+			continue
+		}
+		if !pq.PushItem(item) {
+			return false
+		}
+		pq.tombstones[slot] = nil
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package priorty_queue
+
+import "testing"
+
+func TestRestoreWithinWindow(t *testing.T) {
+	pq := NewPriorityQueue(WithTombstones[string](2))
+	a, b, c := NewItem("a", 30), NewItem("b", 10), NewItem("c", 20)
+	for _, item := range []*Item[string]{a, b, c} {
+		pq.PushItem(item)
+	}
+	pq.RemoveWithTombstone(a)
+	pq.RemoveWithTombstone(b)
+	if !pq.Restore("a") {
+		t.Fatal("Restore(a) = false")
+	}
+	mustValidate(t, pq)
+	if a.Priority() != 30 || !pq.owns(a) {
+		t.Errorf("restored a: priority %d, queued %v", a.Priority(), pq.owns(a))
+	}
+	if pq.Restore("a") {
+		t.Error("second Restore(a) = true")
+	}
+
+	// A third removal pushes b out of the two-slot window.
+	pq.RemoveWithTombstone(c)
+	pq.RemoveWithTombstone(a)
+	if pq.Restore("b") {
+		t.Error("Restore(b) = true after it left the window")
+	}
+}
+
+func TestRestoreSkipsRequeuedItem(t *testing.T) {
+	pq := NewPriorityQueue(WithTombstones[string](4))
+	a := NewItem("a", 1)
+	pq.PushItem(a)
+	pq.RemoveWithTombstone(a)
+	if err := pq.Requeue(a, 5); err != nil {
+		t.Fatal(err)
+	}
+	if pq.Restore("a") {
+		t.Error("Restore(a) = true for an item already requeued")
+	}
+	if pq.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", pq.Len())
+	}
+	mustValidate(t, pq)
+	if a.Priority() != 5 {
+		t.Errorf("priority %d, want the requeued 5", a.Priority())
+	}
+}
+
+func TestPushItemRejectsQueuedItem(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	a := NewItem("a", 1)
+	if !pq.PushItem(a) {
+		t.Fatal("first PushItem = false")
+	}
+	if pq.PushItem(a) || pq.FastPush(a) {
+		t.Error("pushing a queued item again succeeded")
+	}
+	if pq.Len() != 1 {
+		t.Errorf("Len = %d, want 1", pq.Len())
+	}
+}
@@ -0,0 +1,85 @@
+package priorty_queue
+
+// NewWeightedItem returns an Item like NewItem that also carries weight, a
+// grace period in milliseconds used by MinEffectivePriority so that, say,
+// high-traffic tenants are purged a little later than others with the same
+// timestamp.
+func NewWeightedItem[T any](value T, priority, weight int64) *Item[T] {
+	return &Item[T]{value: value, priority: priority, ext: &itemExt{weight: weight}}
+}
+
+// Weight returns the item's weight.
+func (it *Item[T]) Weight() int64 {
+	if it.ext == nil {
+		return 0
+	}
+	return it.ext.weight
+}
+
+// SetWeight changes the item's weight without touching any queue, restoring
+// its full grace. Like SetPriority, it leaves a queued item out of order
+// until FixAll repairs the heap.
+func (it *Item[T]) SetWeight(weight int64) {
+	if weight != 0 || it.ext != nil {
+		ext := it.extended()
+		ext.weight, ext.graceLost = weight, 0
+	}
+}
+
+// grace returns the part of a positive weight still in effect as of
+// nowMillis. It is the full weight for an item that is no older than its
+// priority timestamp and decays as the item ages, halving once the age
+// equals the weight: weight*weight/(weight+age). A weight of 0 or less is
+// an ordinary offset and does not decay.
+func grace[T any](it *Item[T], nowMillis int64) int64 {
+	w := it.Weight()
+	if w <= 0 {
+		return w
+	}
+	age := max(saturatingAdd(nowMillis, -it.priority), 0)
+	return int64(float64(w) * (float64(w) / (float64(w) + float64(age))))
+}
+
+// effectivePriority is the priority it orders by under MinEffectivePriority
+// as of nowMillis: its raw priority pushed later by its remaining grace,
+// saturating instead of overflowing.
+func effectivePriority[T any](it *Item[T], nowMillis int64) int64 {
+	return saturatingAdd(it.priority, grace(it, nowMillis))
+}
+
+// fixedPriority is effectivePriority as of the last FixAll, which is what
+// MinEffectivePriority compares. An item FixAll has not seen yet keeps its
+// full weight.
+func fixedPriority[T any](it *Item[T]) int64 {
+	if it.ext == nil {
+		return it.priority
+	}
+	return saturatingAdd(it.priority, it.ext.weight-it.ext.graceLost)
+}
+
+// MinEffectivePriority orders items by effective priority, lowest first: the
+// raw priority plus whatever grace the item's weight still gives it as of
+// the last FixAll (the full weight before the first). Items whose effective
+// priorities are equal fall back to raw priority, so the lighter of two
+// equal sums goes first.
+func MinEffectivePriority[T any](a, b *Item[T]) bool {
+	ea, eb := fixedPriority(a), fixedPriority(b)
+	if ea != eb {
+		return ea < eb
+	}
+	return a.priority < b.priority
+}
+
+// FixAll recomputes every item's effective priority as of nowMillis, so
+// that weights decay with age, and re-heapifies in O(n). Call it
+// periodically, and after weights or priorities were changed out-of-band
+// with SetWeight or SetPriority. It is meant for queues built
+// WithComparator(MinEffectivePriority); raw priorities are left unchanged.
+func (pq *PriorityQueue[T]) FixAll(nowMillis int64) {
+	for _, item := range pq.items {
+		if item.ext != nil {
+			item.ext.graceLost = item.ext.weight - grace(item, nowMillis)
+		}
+	}
+	pq.Init()
+}
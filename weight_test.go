@@ -0,0 +1,81 @@
+package priorty_queue
+
+import (
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestMinEffectivePriorityWeights(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MinEffectivePriority[string]))
+	pq.PushItem(NewWeightedItem("heavy", 100, 50))
+	pq.PushItem(NewWeightedItem("light", 100, 5))
+	pq.PushItem(NewItem("plain", 100))
+	pq.FixAll(100)
+	mustValidate(t, pq)
+	if got, want := popValues(pq), []string{"plain", "light", "heavy"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestMinEffectivePriorityEqualWeightsKeepRawOrder(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MinEffectivePriority[string]))
+	pq.PushItem(NewWeightedItem("newer", 95, 20))
+	pq.PushItem(NewWeightedItem("older", 90, 20))
+	pq.FixAll(100)
+	if got, want := popValues(pq), []string{"older", "newer"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestSetWeightThenFixAll(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MinEffectivePriority[string]))
+	a, b := NewItem("a", 10), NewItem("b", 20)
+	pq.PushItem(a)
+	pq.PushItem(b)
+	a.SetWeight(100)
+	pq.FixAll(100)
+	mustValidate(t, pq)
+	if got, want := popValues(pq), []string{"b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("pop order %v, want %v", got, want)
+	}
+}
+
+func TestFixAllDecaysWeights(t *testing.T) {
+	pq := NewPriorityQueue(WithComparator(MinEffectivePriority[string]))
+	shielded := NewWeightedItem("shielded", 100, 1000)
+	pq.PushItem(shielded)
+	pq.PushItem(NewItem("plain", 500))
+	pq.PushItem(NewWeightedItem("heavy-twin", 300, 80))
+	pq.PushItem(NewWeightedItem("light-twin", 300, 40))
+
+	// Fresh, the full 1000 ms grace puts shielded behind plain.
+	pq.FixAll(100)
+	if got, want := pq.PopSequence(), "light-twin:300\nheavy-twin:300\nplain:500\nshielded:100\n"; got != want {
+		t.Errorf("fresh order:\n%s\nwant\n%s", got, want)
+	}
+	if got := effectivePriority(shielded, 100); got != 1100 {
+		t.Errorf("effectivePriority at age 0 = %d, want 1100", got)
+	}
+
+	// At age 1000 half the grace is gone, at age 2000 two thirds, which
+	// moves shielded ahead of plain; the heavier twin still goes later.
+	if got := effectivePriority(shielded, 1100); got != 600 {
+		t.Errorf("effectivePriority at age 1000 = %d, want 600", got)
+	}
+	pq.FixAll(2100)
+	mustValidate(t, pq)
+	if got, want := popValues(pq), []string{"light-twin", "heavy-twin", "shielded", "plain"}; !slices.Equal(got, want) {
+		t.Errorf("aged order %v, want %v", got, want)
+	}
+}
+
+func TestItemSizeExcludesOptInState(t *testing.T) {
+	// value, priority, index, seq and the pointer to the opt-in state.
+	if got := unsafe.Sizeof(Item[string]{}); got > 48 {
+		t.Errorf("Item[string] is %d bytes, want at most 48", got)
+	}
+	if NewItem("a", 1).ext != nil {
+		t.Error("NewItem allocated opt-in state")
+	}
+}
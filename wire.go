@@ -0,0 +1,159 @@
+package priorty_queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// wireMagic and wireVersion open every EncodeTo stream.
+const (
+	wireMagic   = "PQWC"
+	wireVersion = 1
+)
+
+// ErrChecksum is returned by DecodeFrom when the stream's CRC32 does not
+// match its contents.
+var ErrChecksum = errors.New("priority queue: checksum mismatch")
+
+// EncodeTo writes the queue to w in a compact, checksummed wire format meant
+// for append-only logs: the 4-byte magic "PQWC" and a version byte, then a
+// payload of a uvarint item count followed, for each item in pop order, by a
+// uvarint length, that many bytes of JSON-encoded value and a varint
+// priority, and finally the big-endian CRC32 (IEEE) of the payload. Varints
+// keep clustered timestamps small. The queue is not modified.
+func (pq *PriorityQueue[T]) EncodeTo(w io.Writer) error {
+	if _, err := w.Write(append([]byte(wireMagic), wireVersion)); err != nil {
+		return err
+	}
+	sum := crc32.NewIEEE()
+	out := io.MultiWriter(w, sum)
+	buf := binary.AppendUvarint(nil, uint64(pq.Len()))
+	var err error
+	pq.walk(func(item *Item[T]) bool {
+		var value []byte
+		if value, err = json.Marshal(item.value); err != nil {
+			return false
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(value)))
+		buf = append(buf, value...)
+		buf = binary.AppendVarint(buf, item.priority)
+		if _, err = out.Write(buf); err != nil {
+			return false
+		}
+		buf = buf[:0]
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if len(buf) > 0 {
+		// Nothing was walked, so the count is still unwritten.
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(sum.Sum(nil))
+	return err
+}
+
+// DecodeFrom reads a queue written by EncodeTo from r and rebuilds it as a
+// min-heap. It returns ErrChecksum if the CRC does not match, and another
+// error for a bad header or a truncated or malformed stream. When r is not an
+// io.ByteReader it is buffered, so DecodeFrom may read past the end of the
+// encoded queue.
+func DecodeFrom[T any](r io.Reader) (*PriorityQueue[T], error) {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	header := make([]byte, len(wireMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("wire: reading header: %w", noEOF(err))
+	}
+	if string(header[:len(wireMagic)]) != wireMagic {
+		return nil, errors.New("wire: bad magic")
+	}
+	if v := header[len(wireMagic)]; v != wireVersion {
+		return nil, fmt.Errorf("wire: unsupported version %d", v)
+	}
+
+	cr := &crcReader{r: br, sum: crc32.NewIEEE()}
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return nil, fmt.Errorf("wire: reading count: %w", noEOF(err))
+	}
+	// The count is not yet verified, so do not trust it for allocation.
+	items := make([]*Item[T], 0, min(count, 1024))
+	var value bytes.Buffer
+	for i := uint64(0); i < count; i++ {
+		n, err := binary.ReadUvarint(cr)
+		if err != nil {
+			return nil, fmt.Errorf("wire: item %d: %w", i, noEOF(err))
+		}
+		value.Reset()
+		if _, err := io.CopyN(&value, cr, int64(n)); err != nil {
+			return nil, fmt.Errorf("wire: item %d: %w", i, noEOF(err))
+		}
+		priority, err := binary.ReadVarint(cr)
+		if err != nil {
+			return nil, fmt.Errorf("wire: item %d: %w", i, noEOF(err))
+		}
+		item := &Item[T]{priority: priority}
+		if err := json.Unmarshal(value.Bytes(), &item.value); err != nil {
+			return nil, fmt.Errorf("wire: item %d: %w", i, err)
+		}
+		items = append(items, item)
+	}
+	want := cr.sum.Sum32()
+	var trailer [4]byte
+	if _, err := io.ReadFull(br, trailer[:]); err != nil {
+		return nil, fmt.Errorf("wire: reading checksum: %w", noEOF(err))
+	}
+	if binary.BigEndian.Uint32(trailer[:]) != want {
+		return nil, ErrChecksum
+	}
+	pq := &PriorityQueue[T]{}
+	pq.load(items)
+	return pq, nil
+}
+
+// byteReader is what DecodeFrom needs to read varints without overreading.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// crcReader checksums everything read through it.
+type crcReader struct {
+	r   byteReader
+	sum hash.Hash32
+}
+
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.sum.Write(p[:n])
+	return n, err
+}
+
+func (c *crcReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.sum.Write([]byte{b})
+	}
+	return b, err
+}
+
+// noEOF reports a clean EOF in the middle of a stream as truncation.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package priorty_queue
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestWireRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 500} {
+		pq := NewPriorityQueue[string]()
+		for i := range n {
+			pq.PushValue(string(rune('a'+i%26)), 1_700_000_000_000+int64(i*7919%1000)-500)
+		}
+		var buf bytes.Buffer
+		if err := pq.EncodeTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecodeFrom[string](&buf)
+		if err != nil {
+			t.Fatalf("%d items: DecodeFrom = %v", n, err)
+		}
+		mustValidate(t, got)
+		if !got.Equal(pq) {
+			t.Errorf("%d items: decoded queue differs", n)
+		}
+		if a, b := popValues(pq.Clone()), popValues(got); !slices.Equal(a, b) {
+			t.Errorf("%d items: decoded pop order differs", n)
+		}
+	}
+}
+
+func TestWireDetectsCorruption(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	pq.PushValue("hello", 10)
+	pq.PushValue("world", 20)
+	var buf bytes.Buffer
+	if err := pq.EncodeTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	flipped := slices.Clone(data)
+	i := bytes.Index(flipped, []byte("hello"))
+	flipped[i] ^= 0x20 // still valid JSON: "Hello"
+	if _, err := DecodeFrom[string](bytes.NewReader(flipped)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("DecodeFrom with a flipped byte = %v, want ErrChecksum", err)
+	}
+
+	badMagic := slices.Clone(data)
+	badMagic[0] = 'X'
+	if _, err := DecodeFrom[string](bytes.NewReader(badMagic)); err == nil {
+		t.Error("DecodeFrom with bad magic = nil error")
+	}
+	for n := range len(data) {
+		_, err := DecodeFrom[string](bytes.NewReader(data[:n]))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("DecodeFrom of %d-byte prefix = %v, want ErrUnexpectedEOF", n, err)
+		}
+	}
+}